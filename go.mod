@@ -3,19 +3,19 @@ module github.com/hgiasac/hasura-utils
 go 1.21
 
 require (
+	github.com/google/uuid v1.6.0
 	github.com/hasura/go-graphql-client v0.12.2
 	github.com/hgiasac/graphql-utils v0.1.0
 	github.com/hgiasac/hasura-router v0.0.0-20240503022940-a7d451a5e2ec
 	go.opentelemetry.io/otel v1.27.0
+	go.opentelemetry.io/otel/metric v1.27.0
 	go.opentelemetry.io/otel/trace v1.27.0
 	gotest.tools/v3 v3.5.1
+	nhooyr.io/websocket v1.8.11
 )
 
 require (
 	github.com/go-logr/logr v1.4.2 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/google/go-cmp v0.6.0 // indirect
-	github.com/google/uuid v1.6.0 // indirect
-	go.opentelemetry.io/otel/metric v1.27.0 // indirect
-	nhooyr.io/websocket v1.8.11 // indirect
 )
@@ -1,38 +1,151 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
 
-// EncodePostgresArray encode array string to postgres array
+// EncodePostgresArray encode array string to postgres array.
+// Elements are quoted and escaped if they are empty, NULL or contain special characters
 func EncodePostgresArray(input []string) string {
-	return fmt.Sprintf("{%s}", strings.Join(input, ","))
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, item := range input {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		if !needsPostgresArrayQuote(item) {
+			sb.WriteString(item)
+			continue
+		}
+		sb.WriteByte('"')
+		for _, c := range item {
+			if c == '"' || c == '\\' {
+				sb.WriteByte('\\')
+			}
+			sb.WriteRune(c)
+		}
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
 }
 
-// DecodePostgresArray decode postgres array string
+func needsPostgresArrayQuote(item string) bool {
+	if item == "" || strings.EqualFold(item, "null") {
+		return true
+	}
+	return strings.ContainsAny(item, "{},\"\\ \t\n\r\v\f")
+}
+
+// DecodePostgresArray decode postgres array string.
+// Quoted elements are unescaped, whitespace around unquoted elements is trimmed.
+// NULL elements and multi-dimensional arrays aren't supported
 func DecodePostgresArray(input string) ([]string, error) {
-	if input == "{}" {
+	value := strings.TrimSpace(input)
+	if len(value) < 2 || value[0] != '{' || value[len(value)-1] != '}' {
+		return nil, fmt.Errorf("invalid postgres array: %s", input)
+	}
+	body := value[1 : len(value)-1]
+	if strings.TrimSpace(body) == "" {
 		return []string{}, nil
 	}
 
-	if len(input) < 3 || input[0] != '{' || input[len(input)-1] != '}' {
-		return nil, fmt.Errorf("invalid postgres array: %s", input)
+	results := []string{}
+	for i := 0; ; {
+		item, next, err := decodePostgresArrayItem(body, i)
+		if err != nil {
+			return nil, fmt.Errorf("invalid postgres array: %s: %w", input, err)
+		}
+		results = append(results, item)
+		if next >= len(body) {
+			return results, nil
+		}
+		// skip the delimiter
+		i = next + 1
+	}
+}
+
+// decodePostgresArrayItem decodes the element that starts at the index
+// and returns the index of the following delimiter or the end of the input
+func decodePostgresArrayItem(body string, start int) (string, int, error) {
+	i := start
+	for i < len(body) && isPostgresArraySpace(body[i]) {
+		i++
 	}
+	if i == len(body) || body[i] == ',' {
+		return "", i, errors.New("empty element")
+	}
+
+	var sb strings.Builder
+	if body[i] == '"' {
+		i++
+		for ; i < len(body) && body[i] != '"'; i++ {
+			if body[i] == '\\' {
+				i++
+				if i == len(body) {
+					break
+				}
+			}
+			sb.WriteByte(body[i])
+		}
+		if i >= len(body) {
+			return "", i, errors.New("unterminated quoted element")
+		}
+		i++
+		for i < len(body) && isPostgresArraySpace(body[i]) {
+			i++
+		}
+		if i < len(body) && body[i] != ',' {
+			return "", i, fmt.Errorf("unexpected character %q after quoted element", body[i])
+		}
+		return sb.String(), i, nil
+	}
+
+	// the length of the element without trailing whitespace, escaped whitespace is kept.
+	// Escaped NULL is a string
+	end := 0
+	for ; i < len(body) && body[i] != ','; i++ {
+		escaped := false
+		switch body[i] {
+		case '{', '}':
+			return "", i, errors.New("multi-dimensional arrays are not supported")
+		case '"':
+			return "", i, errors.New("unexpected quote in unquoted element")
+		case '\\':
+			i++
+			if i == len(body) {
+				return "", i, errors.New("unterminated escape")
+			}
+			escaped = true
+		}
+		sb.WriteByte(body[i])
+		if escaped || !isPostgresArraySpace(body[i]) {
+			end = sb.Len()
+		}
+	}
+	item := sb.String()[:end]
+	if strings.EqualFold(item, "null") && !strings.Contains(body[start:i], "\\") {
+		return "", i, errors.New("null elements are not supported")
+	}
+	return item, i, nil
+}
 
-	return strings.Split(input[1:len(input)-1], ","), nil
+func isPostgresArraySpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	default:
+		return false
+	}
 }
 
 // EncodePostgresArrayStringer the generic function to encode postgres array from Stringer implementations
 func EncodePostgresArrayStringer[V fmt.Stringer](inputs []V) string {
-	length := len(inputs)
-	if length == 0 {
-		return "{}"
-	}
-
-	sInputs := make([]string, length)
+	sInputs := make([]string, len(inputs))
 	for i, u := range inputs {
 		sInputs[i] = u.String()
 	}
-	return fmt.Sprintf("{%s}", strings.Join(sInputs, ","))
+	return EncodePostgresArray(sInputs)
 }
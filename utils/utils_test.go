@@ -16,3 +16,27 @@ func TestPostgresArray(t *testing.T) {
 	assert.DeepEqual(t, arr, []string{"a", "b", "c"})
 	assert.DeepEqual(t, EncodePostgresArray(arr), "{a,b,c}")
 }
+
+func TestPostgresArray_Quoting(t *testing.T) {
+	values := []string{"a b", `say "hi"`, `c:\dir`, "x,y", "{z}", "", "NULL", "plain"}
+	encoded := EncodePostgresArray(values)
+	assert.Equal(t, `{"a b","say \"hi\"","c:\\dir","x,y","{z}","","NULL",plain}`, encoded)
+
+	decoded, err := DecodePostgresArray(encoded)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, values, decoded)
+
+	decoded, err = DecodePostgresArray(`{ a , "b" ,c\,d, e\ }`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"a", "b", "c,d", "e "}, decoded)
+
+	decoded, err = DecodePostgresArray("{}")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{}, decoded)
+	assert.Equal(t, "{}", EncodePostgresArray(nil))
+
+	for _, input := range []string{"", "a", "{a", `{"a}`, "{a,}", "{,a}", "{{a},{b}}", "{NULL}", `{"a"b}`, `{a"b}`} {
+		_, err := DecodePostgresArray(input)
+		assert.Assert(t, err != nil, input)
+	}
+}
@@ -0,0 +1,322 @@
+// Package boolexp builds Hasura boolean expressions, e.g. where arguments and permission filters
+package boolexp
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hgiasac/hasura-utils/v2/types"
+)
+
+// operators of Hasura boolean expressions
+const (
+	OpAnd    = "_and"
+	OpOr     = "_or"
+	OpNot    = "_not"
+	OpExists = "_exists"
+
+	OpEq     = "_eq"
+	OpNeq    = "_neq"
+	OpGt     = "_gt"
+	OpGte    = "_gte"
+	OpLt     = "_lt"
+	OpLte    = "_lte"
+	OpIn     = "_in"
+	OpNin    = "_nin"
+	OpIsNull = "_is_null"
+
+	OpLike     = "_like"
+	OpNlike    = "_nlike"
+	OpIlike    = "_ilike"
+	OpNilike   = "_nilike"
+	OpSimilar  = "_similar"
+	OpNsimilar = "_nsimilar"
+	OpRegex    = "_regex"
+	OpNregex   = "_nregex"
+	OpIregex   = "_iregex"
+	OpNiregex  = "_niregex"
+
+	OpContains    = "_contains"
+	OpContainedIn = "_contained_in"
+	OpHasKey      = "_has_key"
+	OpHasKeysAny  = "_has_keys_any"
+	OpHasKeysAll  = "_has_keys_all"
+
+	OpCeq  = "_ceq"
+	OpCneq = "_cneq"
+	OpCgt  = "_cgt"
+	OpCgte = "_cgte"
+	OpClt  = "_clt"
+	OpClte = "_clte"
+)
+
+// Expression represents a Hasura boolean expression. It's assignable to map[string]any fields,
+// e.g. types.UpdateManyInput.Where
+type Expression map[string]any
+
+// MarshalJSON implements the json Marshaler interface
+func (exp Expression) MarshalJSON() ([]byte, error) {
+	if exp == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(map[string]any(exp))
+}
+
+// And combines expressions with the _and operator
+func And(expressions ...Expression) Expression {
+	return Expression{OpAnd: nonNilExpressions(expressions)}
+}
+
+// Or combines expressions with the _or operator
+func Or(expressions ...Expression) Expression {
+	return Expression{OpOr: nonNilExpressions(expressions)}
+}
+
+// Not negates the expression with the _not operator
+func Not(expression Expression) Expression {
+	if expression == nil {
+		expression = Expression{}
+	}
+	return Expression{OpNot: expression}
+}
+
+// Relationship traverses the relationship. Multiple expressions are combined with the _and operator.
+// For array relationships the expression matches if any related row matches
+func Relationship(name string, expressions ...Expression) Expression {
+	if len(expressions) == 1 && expressions[0] != nil {
+		return Expression{name: expressions[0]}
+	}
+	return Expression{name: And(expressions...)}
+}
+
+// Exists checks if any row of the unrelated table matches the expression
+func Exists(schema string, table string, where Expression) Expression {
+	if where == nil {
+		where = Expression{}
+	}
+	return Expression{
+		OpExists: map[string]any{
+			"_table": map[string]string{
+				"schema": schema,
+				"name":   table,
+			},
+			"_where": where,
+		},
+	}
+}
+
+func nonNilExpressions(expressions []Expression) []Expression {
+	results := make([]Expression, 0, len(expressions))
+	for _, exp := range expressions {
+		if exp != nil {
+			results = append(results, exp)
+		}
+	}
+	return results
+}
+
+// Field represents a column of the table to compare
+type Field string
+
+func (f Field) compare(op string, value any) Expression {
+	return Expression{
+		string(f): map[string]any{op: normalizeValue(value)},
+	}
+}
+
+// Compare compares the field with the custom operator, e.g. _st_intersects of PostGIS
+func (f Field) Compare(op string, value any) Expression {
+	return f.compare(op, value)
+}
+
+// Eq compares the field with the _eq operator
+func (f Field) Eq(value any) Expression {
+	return f.compare(OpEq, value)
+}
+
+// Neq compares the field with the _neq operator
+func (f Field) Neq(value any) Expression {
+	return f.compare(OpNeq, value)
+}
+
+// Gt compares the field with the _gt operator
+func (f Field) Gt(value any) Expression {
+	return f.compare(OpGt, value)
+}
+
+// Gte compares the field with the _gte operator
+func (f Field) Gte(value any) Expression {
+	return f.compare(OpGte, value)
+}
+
+// Lt compares the field with the _lt operator
+func (f Field) Lt(value any) Expression {
+	return f.compare(OpLt, value)
+}
+
+// Lte compares the field with the _lte operator
+func (f Field) Lte(value any) Expression {
+	return f.compare(OpLte, value)
+}
+
+// In checks if the field equals any of values with the _in operator.
+// Compare the field with OpIn and the name of an array session variable to check the values of the session
+func (f Field) In(values ...any) Expression {
+	return f.compare(OpIn, values)
+}
+
+// Nin checks if the field equals none of values with the _nin operator
+func (f Field) Nin(values ...any) Expression {
+	return f.compare(OpNin, values)
+}
+
+// IsNull checks if the field is null, or isn't null if the value is false, with the _is_null operator
+func (f Field) IsNull(value bool) Expression {
+	return f.compare(OpIsNull, value)
+}
+
+// Like matches the LIKE pattern with the _like operator. Escape user input with EscapeLike
+func (f Field) Like(pattern string) Expression {
+	return f.compare(OpLike, pattern)
+}
+
+// Nlike matches the NOT LIKE pattern with the _nlike operator
+func (f Field) Nlike(pattern string) Expression {
+	return f.compare(OpNlike, pattern)
+}
+
+// Ilike matches the case-insensitive LIKE pattern with the _ilike operator
+func (f Field) Ilike(pattern string) Expression {
+	return f.compare(OpIlike, pattern)
+}
+
+// Nilike matches the case-insensitive NOT LIKE pattern with the _nilike operator
+func (f Field) Nilike(pattern string) Expression {
+	return f.compare(OpNilike, pattern)
+}
+
+// Similar matches the SIMILAR TO pattern with the _similar operator
+func (f Field) Similar(pattern string) Expression {
+	return f.compare(OpSimilar, pattern)
+}
+
+// Nsimilar matches the NOT SIMILAR TO pattern with the _nsimilar operator
+func (f Field) Nsimilar(pattern string) Expression {
+	return f.compare(OpNsimilar, pattern)
+}
+
+// Regex matches the POSIX regular expression with the _regex operator
+func (f Field) Regex(pattern string) Expression {
+	return f.compare(OpRegex, pattern)
+}
+
+// Nregex doesn't match the POSIX regular expression with the _nregex operator
+func (f Field) Nregex(pattern string) Expression {
+	return f.compare(OpNregex, pattern)
+}
+
+// Iregex matches the case-insensitive POSIX regular expression with the _iregex operator
+func (f Field) Iregex(pattern string) Expression {
+	return f.compare(OpIregex, pattern)
+}
+
+// Niregex doesn't match the case-insensitive POSIX regular expression with the _niregex operator
+func (f Field) Niregex(pattern string) Expression {
+	return f.compare(OpNiregex, pattern)
+}
+
+// Contains checks if the jsonb or array field contains the value with the _contains operator
+func (f Field) Contains(value any) Expression {
+	return f.compare(OpContains, value)
+}
+
+// ContainedIn checks if the jsonb or array field is contained in the value with the _contained_in operator
+func (f Field) ContainedIn(value any) Expression {
+	return f.compare(OpContainedIn, value)
+}
+
+// HasKey checks if the jsonb field has the top-level key with the _has_key operator
+func (f Field) HasKey(key string) Expression {
+	return f.compare(OpHasKey, key)
+}
+
+// HasKeysAny checks if the jsonb field has any of top-level keys with the _has_keys_any operator
+func (f Field) HasKeysAny(keys ...string) Expression {
+	return f.compare(OpHasKeysAny, keys)
+}
+
+// HasKeysAll checks if the jsonb field has all top-level keys with the _has_keys_all operator
+func (f Field) HasKeysAll(keys ...string) Expression {
+	return f.compare(OpHasKeysAll, keys)
+}
+
+// Values converts the slice to arguments of In and Nin
+func Values[T any](values []T) []any {
+	results := make([]any, len(values))
+	for i, v := range values {
+		results[i] = v
+	}
+	return results
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escapes the backslash and wildcards of LIKE patterns, so the value is matched literally
+func EscapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// Prefix creates the LIKE pattern that matches values starting with the literal value
+func Prefix(value string) string {
+	return EscapeLike(value) + "%"
+}
+
+// Suffix creates the LIKE pattern that matches values ending with the literal value
+func Suffix(value string) string {
+	return "%" + EscapeLike(value)
+}
+
+// Substring creates the LIKE pattern that matches values containing the literal value
+func Substring(value string) string {
+	return "%" + EscapeLike(value) + "%"
+}
+
+var (
+	dateType    = reflect.TypeOf(types.Date{})
+	datePtrType = reflect.TypeOf(&types.Date{})
+)
+
+// normalizeValue converts date values to strings, because types.Date values don't implement json.Marshaler.
+// Items of slices are converted too
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case types.Date:
+		return v.String()
+	case *types.Date:
+		if v == nil {
+			return nil
+		}
+		return v.String()
+	case time.Time, string, bool, int, int64, float64, json.RawMessage, Expression, map[string]any:
+		return v
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return value
+	}
+	switch elem := rv.Type().Elem(); {
+	case elem == dateType, elem == datePtrType, elem.Kind() == reflect.Interface:
+		results := make([]any, rv.Len())
+		for i := range results {
+			results[i] = normalizeValue(rv.Index(i).Interface())
+		}
+		return results
+	default:
+		return value
+	}
+}
@@ -0,0 +1,108 @@
+package boolexp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hgiasac/hasura-utils/v2/types"
+	"gotest.tools/v3/assert"
+)
+
+func TestExpression(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		Expression Expression
+		Expected   string
+	}{
+		{
+			Name:       "eq",
+			Expression: Field("name").Eq("foo"),
+			Expected:   `{"name":{"_eq":"foo"}}`,
+		},
+		{
+			Name:       "in",
+			Expression: Field("id").In(Values([]int{1, 2})...),
+			Expected:   `{"id":{"_in":[1,2]}}`,
+		},
+		{
+			Name:       "empty_in",
+			Expression: Field("id").Nin(),
+			Expected:   `{"id":{"_nin":[]}}`,
+		},
+		{
+			Name:       "is_null",
+			Expression: Field("deleted_at").IsNull(true),
+			Expected:   `{"deleted_at":{"_is_null":true}}`,
+		},
+		{
+			Name:       "date",
+			Expression: And(Field("due").Gt(types.Date{Year: 2024, Month: 5, Day: 1}), Field("due").Lte(types.MustParseDate("2024-06-01"))),
+			Expected:   `{"_and":[{"due":{"_gt":"2024-05-01"}},{"due":{"_lte":"2024-06-01"}}]}`,
+		},
+		{
+			Name:       "dates_in",
+			Expression: Field("due").In(types.Date{Year: 2024, Month: 5, Day: 1}, nil),
+			Expected:   `{"due":{"_in":["2024-05-01",null]}}`,
+		},
+		{
+			Name:       "time",
+			Expression: Field("created_at").Gte(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)),
+			Expected:   `{"created_at":{"_gte":"2024-05-01T10:00:00Z"}}`,
+		},
+		{
+			Name:       "like",
+			Expression: Or(Field("name").Ilike(Substring("50%_off")), Field("code").Like(Prefix(`a\b`))),
+			Expected:   `{"_or":[{"name":{"_ilike":"%50\\%\\_off%"}},{"code":{"_like":"a\\\\b%"}}]}`,
+		},
+		{
+			Name:       "jsonb",
+			Expression: And(Field("metadata").Contains(map[string]any{"tier": "gold"}), Field("metadata").HasKey("tier"), Field("metadata").HasKeysAll("a", "b")),
+			Expected:   `{"_and":[{"metadata":{"_contains":{"tier":"gold"}}},{"metadata":{"_has_key":"tier"}},{"metadata":{"_has_keys_all":["a","b"]}}]}`,
+		},
+		{
+			Name:       "array",
+			Expression: Field("tags").ContainedIn([]string{"a", "b"}),
+			Expected:   `{"tags":{"_contained_in":["a","b"]}}`,
+		},
+		{
+			Name:       "relationship",
+			Expression: Relationship("author", Field("id").Eq("X-Hasura-User-Id"), Not(Field("banned").Eq(true))),
+			Expected:   `{"author":{"_and":[{"id":{"_eq":"X-Hasura-User-Id"}},{"_not":{"banned":{"_eq":true}}}]}}`,
+		},
+		{
+			Name:       "exists",
+			Expression: Exists("public", "settings", Field("enabled").Eq(true)),
+			Expected:   `{"_exists":{"_table":{"name":"settings","schema":"public"},"_where":{"enabled":{"_eq":true}}}}`,
+		},
+		{
+			Name:       "nil",
+			Expression: nil,
+			Expected:   `{}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			bs, err := json.Marshal(tc.Expression)
+			assert.NilError(t, err)
+			assert.Equal(t, tc.Expected, string(bs))
+		})
+	}
+}
+
+func TestExpression_UpdateManyInput(t *testing.T) {
+	input := types.UpdateManyInput{
+		Where: Field("id").Eq(1),
+		Set:   map[string]any{"name": "foo"},
+	}
+	bs, err := json.Marshal(input)
+	assert.NilError(t, err)
+	assert.Equal(t, `{"where":{"id":{"_eq":1}},"_set":{"name":"foo"}}`, string(bs))
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\%\_a\\b`, EscapeLike(`100%_a\b`))
+	assert.Equal(t, `foo%`, Prefix("foo"))
+	assert.Equal(t, `%foo`, Suffix("foo"))
+}
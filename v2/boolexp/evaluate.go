@@ -0,0 +1,813 @@
+package boolexp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgiasac/hasura-utils/utils"
+	"github.com/hgiasac/hasura-utils/v2/gql"
+)
+
+// comparisonOperators the operators that compare the value of a field
+var comparisonOperators = map[string]bool{
+	OpEq: true, OpNeq: true, OpGt: true, OpGte: true, OpLt: true, OpLte: true,
+	OpIn: true, OpNin: true, OpIsNull: true,
+	OpLike: true, OpNlike: true, OpIlike: true, OpNilike: true, OpSimilar: true, OpNsimilar: true,
+	OpRegex: true, OpNregex: true, OpIregex: true, OpNiregex: true,
+	OpContains: true, OpContainedIn: true, OpHasKey: true, OpHasKeysAny: true, OpHasKeysAll: true,
+	OpCeq: true, OpCneq: true, OpCgt: true, OpCgte: true, OpClt: true, OpClte: true,
+}
+
+// Result represents the evaluation result of a boolean expression
+type Result struct {
+	Matched bool
+	// Failure explains which clause failed if the expression doesn't match
+	Failure *Failure
+}
+
+// Failure explains the clause of the expression that doesn't match
+type Failure struct {
+	// Path the dot-separated path of the field, e.g. author.posts[0].title
+	Path     string
+	Operator string
+	// Expected the argument of the operator after session variables are substituted
+	Expected any
+	// Actual the value of the field
+	Actual  any
+	Message string
+	// Causes failures of nested expressions, e.g. all branches of _or
+	Causes []*Failure
+}
+
+// String implements the Stringer interface
+func (f *Failure) String() string {
+	var sb strings.Builder
+	if f.Path != "" {
+		sb.WriteString(f.Path)
+		sb.WriteString(": ")
+	}
+	if f.Operator != "" {
+		sb.WriteString(f.Operator)
+		sb.WriteString(" ")
+	}
+	sb.WriteString(f.Message)
+	if len(f.Causes) > 0 {
+		causes := make([]string, len(f.Causes))
+		for i, cause := range f.Causes {
+			causes[i] = cause.String()
+		}
+		fmt.Fprintf(&sb, " (%s)", strings.Join(causes, "; "))
+	}
+	return sb.String()
+}
+
+// Evaluate evaluates the boolean expression against the row, without a round trip to Hasura.
+// The expression can be an Expression, a map or JSON bytes. The row can be a struct, a map or JSON bytes,
+// fields are matched by JSON names and missing fields are compared as null. Arguments that are x-hasura-* session variable names,
+// e.g. permission filters, are substituted with values of session variables.
+// Nested expressions of object and array relationships are evaluated against nested objects and arrays of the row.
+// Regular expressions use the Go syntax, _exists isn't supported
+func Evaluate(expression any, row any, sessionVariables gql.SessionVariables) (Result, error) {
+	exp, err := toGenericJSON(expression)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid boolean expression: %w", err)
+	}
+	value, err := toGenericJSON(row)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid row: %w", err)
+	}
+	rowObject, ok := value.(map[string]any)
+	if !ok {
+		return Result{}, fmt.Errorf("row must be an object, got %T", value)
+	}
+
+	ev := &evaluator{
+		sessionVariables: sessionVariables,
+		root:             rowObject,
+	}
+	failure, err := ev.evalExpression(exp, rowObject, "")
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Matched: failure == nil,
+		Failure: failure,
+	}, nil
+}
+
+type evaluator struct {
+	sessionVariables gql.SessionVariables
+	root             map[string]any
+}
+
+func (ev *evaluator) evalExpression(expression any, row map[string]any, path string) (*Failure, error) {
+	exp, ok := expression.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: boolean expression must be an object, got %T", pathOrRoot(path), expression)
+	}
+
+	keys := make([]string, 0, len(exp))
+	for key := range exp {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		failure, err := ev.evalKey(key, exp[key], row, path)
+		if failure != nil || err != nil {
+			return failure, err
+		}
+	}
+	return nil, nil
+}
+
+func (ev *evaluator) evalKey(key string, argument any, row map[string]any, path string) (*Failure, error) {
+	switch key {
+	case OpAnd:
+		expressions, ok := argument.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s must be an array", pathOrRoot(path), key)
+		}
+		for i, exp := range expressions {
+			failure, err := ev.evalExpression(exp, row, joinPath(path, fmt.Sprintf("%s[%d]", key, i)))
+			if failure != nil || err != nil {
+				return failure, err
+			}
+		}
+		return nil, nil
+	case OpOr:
+		expressions, ok := argument.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s must be an array", pathOrRoot(path), key)
+		}
+		var causes []*Failure
+		for i, exp := range expressions {
+			failure, err := ev.evalExpression(exp, row, joinPath(path, fmt.Sprintf("%s[%d]", key, i)))
+			if err != nil {
+				return nil, err
+			}
+			if failure == nil {
+				return nil, nil
+			}
+			causes = append(causes, failure)
+		}
+		return &Failure{
+			Path:     path,
+			Operator: key,
+			Message:  fmt.Sprintf("none of %d expressions matched", len(expressions)),
+			Causes:   causes,
+		}, nil
+	case OpNot:
+		failure, err := ev.evalExpression(argument, row, joinPath(path, key))
+		if err != nil || failure != nil {
+			return nil, err
+		}
+		return &Failure{
+			Path:     path,
+			Operator: key,
+			Message:  "the negated expression matched",
+		}, nil
+	case OpExists:
+		return nil, fmt.Errorf("%s: %s of unrelated tables isn't supported", pathOrRoot(path), key)
+	}
+	if strings.HasPrefix(key, "_") {
+		return nil, fmt.Errorf("%s: unsupported operator %s", pathOrRoot(path), key)
+	}
+
+	fieldPath := joinPath(path, key)
+	value, exists := row[key]
+	if comparison, ok := argument.(map[string]any); ok {
+		// missing columns are null, e.g. nil pointers of structs with omitempty
+		if isComparison(comparison) {
+			return ev.evalComparison(comparison, value, row, fieldPath)
+		}
+		// the empty expression is a no-op filter of columns
+		if _, isArray := value.([]any); len(comparison) == 0 && !isArray {
+			return nil, nil
+		}
+	}
+
+	// the relationship
+	switch v := value.(type) {
+	case map[string]any:
+		return ev.evalExpression(argument, v, fieldPath)
+	case []any:
+		var causes []*Failure
+		for i, item := range v {
+			object, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s[%d]: related row must be an object, got %T", fieldPath, i, item)
+			}
+			failure, err := ev.evalExpression(argument, object, fmt.Sprintf("%s[%d]", fieldPath, i))
+			if err != nil {
+				return nil, err
+			}
+			if failure == nil {
+				return nil, nil
+			}
+			causes = append(causes, failure)
+		}
+		return &Failure{
+			Path:    fieldPath,
+			Message: fmt.Sprintf("none of %d related rows matched", len(v)),
+			Causes:  causes,
+		}, nil
+	case nil:
+		if !exists {
+			return &Failure{Path: fieldPath, Message: "the related row doesn't exist in the row"}, nil
+		}
+		return &Failure{Path: fieldPath, Message: "the related row is null"}, nil
+	default:
+		return nil, fmt.Errorf("%s: expected a relationship object or array, got %T", fieldPath, value)
+	}
+}
+
+// isComparison checks if the expression compares the field value with operators only
+func isComparison(exp map[string]any) bool {
+	if len(exp) == 0 {
+		return false
+	}
+	for key := range exp {
+		if !comparisonOperators[key] {
+			return false
+		}
+	}
+	return true
+}
+
+func (ev *evaluator) evalComparison(comparison map[string]any, value any, row map[string]any, path string) (*Failure, error) {
+	operators := make([]string, 0, len(comparison))
+	for op := range comparison {
+		operators = append(operators, op)
+	}
+	slices.Sort(operators)
+
+	for _, op := range operators {
+		argument, err := ev.substitute(comparison[op])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, op, err)
+		}
+		matched, err := ev.evalOperator(op, argument, value, row)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, op, err)
+		}
+		if !matched {
+			return &Failure{
+				Path:     path,
+				Operator: op,
+				Expected: argument,
+				Actual:   value,
+				Message:  fmt.Sprintf("%s doesn't match %s", formatValue(value), formatValue(argument)),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// substitute replaces x-hasura-* session variable names in the argument, or items of the array argument, with values
+func (ev *evaluator) substitute(argument any) (any, error) {
+	switch arg := argument.(type) {
+	case string:
+		name := strings.ToLower(arg)
+		if !strings.HasPrefix(name, "x-hasura-") {
+			return arg, nil
+		}
+		value, ok := ev.sessionVariables[name]
+		if !ok {
+			value, ok = ev.sessionVariables[arg]
+		}
+		if !ok {
+			return nil, &gql.SessionVariableError{Name: name, Err: gql.ErrSessionVariableNotFound}
+		}
+		return value, nil
+	case []any:
+		results := make([]any, len(arg))
+		for i, item := range arg {
+			value, err := ev.substitute(item)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = value
+		}
+		return results, nil
+	default:
+		return argument, nil
+	}
+}
+
+func (ev *evaluator) evalOperator(op string, argument any, value any, row map[string]any) (bool, error) {
+	if argument == nil && op != OpIsNull {
+		return false, fmt.Errorf("unexpected null argument")
+	}
+
+	switch op {
+	case OpIsNull:
+		isNull, ok := argument.(bool)
+		if !ok {
+			return false, fmt.Errorf("expected a boolean argument, got %T", argument)
+		}
+		return (value == nil) == isNull, nil
+	case OpEq, OpNeq, OpGt, OpGte, OpLt, OpLte:
+		return compareWith(op, value, argument)
+	case OpCeq, OpCneq, OpCgt, OpCgte, OpClt, OpClte:
+		other, err := ev.columnValue(argument, row)
+		if err != nil {
+			return false, err
+		}
+		return compareWith("_"+op[2:], value, other)
+	case OpIn, OpNin:
+		values, err := arrayArgument(argument)
+		if err != nil {
+			return false, err
+		}
+		if value == nil {
+			return false, nil
+		}
+		found := slices.ContainsFunc(values, func(item any) bool {
+			return jsonEqual(value, item)
+		})
+		return found == (op == OpIn), nil
+	case OpLike, OpNlike, OpIlike, OpNilike, OpSimilar, OpNsimilar, OpRegex, OpNregex, OpIregex, OpNiregex:
+		return matchPattern(op, value, argument)
+	case OpContains:
+		return value != nil && containsJSON(value, argument, true), nil
+	case OpContainedIn:
+		return value != nil && containsJSON(argument, value, true), nil
+	case OpHasKey:
+		key, ok := argument.(string)
+		if !ok {
+			return false, fmt.Errorf("expected a string argument, got %T", argument)
+		}
+		return hasKey(value, key), nil
+	case OpHasKeysAny, OpHasKeysAll:
+		keys, err := arrayArgument(argument)
+		if err != nil {
+			return false, err
+		}
+		for _, item := range keys {
+			key, ok := item.(string)
+			if !ok {
+				return false, fmt.Errorf("expected string keys, got %T", item)
+			}
+			if hasKey(value, key) == (op == OpHasKeysAny) {
+				return op == OpHasKeysAny, nil
+			}
+		}
+		return op == OpHasKeysAll, nil
+	default:
+		return false, fmt.Errorf("unsupported operator")
+	}
+}
+
+// columnValue gets the value of the column of the current row, or the root row if the path starts with $
+func (ev *evaluator) columnValue(argument any, row map[string]any) (any, error) {
+	switch arg := argument.(type) {
+	case string:
+		return row[arg], nil
+	case []any:
+		current := row
+		for i, item := range arg {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a column path of strings, got %T", item)
+			}
+			if i == 0 && name == "$" {
+				current = ev.root
+				continue
+			}
+			if i == len(arg)-1 {
+				return current[name], nil
+			}
+			next, ok := current[name].(map[string]any)
+			if !ok {
+				return nil, nil
+			}
+			current = next
+		}
+		return nil, fmt.Errorf("column path is empty")
+	default:
+		return nil, fmt.Errorf("expected a column name, got %T", argument)
+	}
+}
+
+// arrayArgument gets items of the array argument. Strings are decoded as Postgres array literals, e.g. {1,2} or {"a b"},
+// which are values of array session variables
+func arrayArgument(argument any) ([]any, error) {
+	switch arg := argument.(type) {
+	case []any:
+		return arg, nil
+	case string:
+		items, err := utils.DecodePostgresArray(arg)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]any, len(items))
+		for i, item := range items {
+			results[i] = item
+		}
+		return results, nil
+	default:
+		return nil, fmt.Errorf("expected an array argument, got %T", argument)
+	}
+}
+
+// compareWith compares the value with the argument. Comparisons with null never match
+func compareWith(op string, value any, argument any) (bool, error) {
+	if value == nil || argument == nil {
+		return false, nil
+	}
+	if op == OpEq || op == OpNeq {
+		return jsonEqual(value, argument) == (op == OpEq), nil
+	}
+
+	cmp, ok := compareScalars(value, argument, true)
+	if !ok {
+		return false, fmt.Errorf("can't compare %s with %s", formatValue(value), formatValue(argument))
+	}
+	switch op {
+	case OpGt:
+		return cmp > 0, nil
+	case OpGte:
+		return cmp >= 0, nil
+	case OpLt:
+		return cmp < 0, nil
+	default:
+		return cmp <= 0, nil
+	}
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999", time.DateOnly}
+
+// compareScalars compares numbers, timestamps, strings and booleans. A string is cast to the type
+// of the other value if it's a number or a boolean, like session variables that Hasura casts to column types.
+// Strings are compared as timestamps by ordering operators. Equality checks compare them as timestamps only
+// if both have the same layout, so text like 2024-01-01 doesn't equal 2024-01-01T00:00:00Z
+func compareScalars(a any, b any, ordering bool) (int, bool) {
+	_, numberA := a.(json.Number)
+	_, numberB := b.(json.Number)
+	if numberA || numberB {
+		ra, okA := toRat(a)
+		rb, okB := toRat(b)
+		if !okA || !okB {
+			return 0, false
+		}
+		return ra.Cmp(rb), true
+	}
+
+	_, boolA := a.(bool)
+	_, boolB := b.(bool)
+	if boolA || boolB {
+		ba, okA := toBool(a)
+		bb, okB := toBool(b)
+		switch {
+		case !okA || !okB:
+			return 0, false
+		case ba == bb:
+			return 0, true
+		case bb:
+			return -1, true
+		default:
+			return 1, true
+		}
+	}
+
+	sa, okA := a.(string)
+	sb, okB := b.(string)
+	if !okA || !okB {
+		return 0, false
+	}
+	if ta, layoutA, ok := parseTime(sa); ok {
+		if tb, layoutB, ok := parseTime(sb); ok && (ordering || layoutA == layoutB) {
+			return ta.Compare(tb), true
+		}
+	}
+	return strings.Compare(sa, sb), true
+}
+
+func toRat(value any) (*big.Rat, bool) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = strings.TrimSpace(v)
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+func toBool(value any) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	default:
+		return false, false
+	}
+}
+
+// parseTime parses the timestamp and returns the index of the matched layout
+func parseTime(value string) (time.Time, int, bool) {
+	for i, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, i, true
+		}
+	}
+	return time.Time{}, -1, false
+}
+
+// jsonEqual checks if generic JSON values are equal. Scalars are compared with casting
+func jsonEqual(a any, b any) bool {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, item := range av {
+			other, ok := bv[key]
+			if !ok || !jsonEqual(item, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return b == nil
+	}
+	switch b.(type) {
+	case map[string]any, []any, nil:
+		return false
+	}
+	cmp, ok := compareScalars(a, b, false)
+	return ok && cmp == 0
+}
+
+// containsJSON implements the containment of Postgres jsonb and arrays.
+// The top-level array contains a scalar if any item equals it
+func containsJSON(container any, contained any, topLevel bool) bool {
+	switch cv := contained.(type) {
+	case map[string]any:
+		object, ok := container.(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, item := range cv {
+			other, ok := object[key]
+			if !ok || !containsJSON(other, item, false) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		array, ok := container.([]any)
+		if !ok {
+			return false
+		}
+		for _, item := range cv {
+			if !slices.ContainsFunc(array, func(other any) bool {
+				return containsJSON(other, item, false)
+			}) {
+				return false
+			}
+		}
+		return true
+	default:
+		if array, ok := container.([]any); ok && topLevel {
+			return slices.ContainsFunc(array, func(other any) bool {
+				return jsonEqual(other, contained)
+			})
+		}
+		return jsonEqual(container, contained)
+	}
+}
+
+// hasKey checks if the object has the key, or the array has the string item
+func hasKey(value any, key string) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		_, ok := v[key]
+		return ok
+	case []any:
+		return slices.Contains(v, any(key))
+	default:
+		return false
+	}
+}
+
+func matchPattern(op string, value any, argument any) (bool, error) {
+	pattern, ok := argument.(string)
+	if !ok {
+		return false, fmt.Errorf("expected a string pattern, got %T", argument)
+	}
+	if value == nil {
+		return false, nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		text = formatValue(value)
+	}
+
+	var expr string
+	negated := false
+	switch op {
+	case OpLike, OpNlike, OpIlike, OpNilike:
+		expr = "^" + likeToRegexp(pattern) + "$"
+		negated = op == OpNlike || op == OpNilike
+		if op == OpIlike || op == OpNilike {
+			expr = "(?i)" + expr
+		}
+	case OpSimilar, OpNsimilar:
+		similar, err := similarToRegexp(pattern)
+		if err != nil {
+			return false, err
+		}
+		expr = "^(?:" + similar + ")$"
+		negated = op == OpNsimilar
+	default:
+		expr = pattern
+		negated = op == OpNregex || op == OpNiregex
+		if op == OpIregex || op == OpNiregex {
+			expr = "(?i)" + expr
+		}
+	}
+
+	re, err := regexp.Compile("(?s)" + expr)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(text) != negated, nil
+}
+
+// likeToRegexp converts the LIKE pattern with the backslash escape character to the regular expression
+func likeToRegexp(pattern string) string {
+	var sb strings.Builder
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case r == '%':
+			sb.WriteString(".*")
+		case r == '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return sb.String()
+}
+
+// similarToRegexp converts the SIMILAR TO pattern to the regular expression
+func similarToRegexp(pattern string) (string, error) {
+	var sb strings.Builder
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case r == '%':
+			sb.WriteString(".*")
+		case r == '_':
+			sb.WriteString(".")
+		case strings.ContainsRune("|*+?(){}", r):
+			sb.WriteRune(r)
+		case r == '[':
+			end, err := bracketExpressionEnd(runes, i)
+			if err != nil {
+				return "", err
+			}
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			sb.WriteString(string(runes[i : end+1]))
+			i = end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return sb.String(), nil
+}
+
+// bracketExpressionEnd returns the index of the bracket that closes the bracket expression at the start index,
+// or -1 if it isn't closed. A leading ] is a literal member and POSIX character classes such as [:alpha:] are kept.
+// Equivalence classes and collating elements aren't supported
+func bracketExpressionEnd(runes []rune, start int) (int, error) {
+	i := start + 1
+	if i < len(runes) && runes[i] == '^' {
+		i++
+	}
+	if i < len(runes) && runes[i] == ']' {
+		i++
+	}
+	for i < len(runes) {
+		switch {
+		case runes[i] == ']':
+			return i, nil
+		case runes[i] == '\\' && i+1 < len(runes):
+			i += 2
+		case runes[i] == '[' && i+1 < len(runes) && strings.ContainsRune(":=.", runes[i+1]):
+			delimiter := runes[i+1]
+			end := -1
+			for j := i + 2; j+1 < len(runes); j++ {
+				if runes[j] == delimiter && runes[j+1] == ']' {
+					end = j + 1
+					break
+				}
+			}
+			if end < 0 {
+				return -1, nil
+			}
+			if delimiter != ':' {
+				return -1, fmt.Errorf("unsupported bracket element %s in SIMILAR TO pattern", string(runes[i:end+1]))
+			}
+			i = end + 1
+		default:
+			i++
+		}
+	}
+	return -1, nil
+}
+
+// toGenericJSON converts the value to generic JSON values with json.Number numbers.
+// Structs are marshaled through pointers, so pointer-receiver marshalers like types.Date apply
+func toGenericJSON(value any) (any, error) {
+	var data []byte
+	switch v := value.(type) {
+	case json.RawMessage:
+		data = v
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		rv := reflect.ValueOf(value)
+		if rv.IsValid() && rv.Kind() == reflect.Struct {
+			ptr := reflect.New(rv.Type())
+			ptr.Elem().Set(rv)
+			value = ptr.Interface()
+		}
+		bs, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		data = bs
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var result any
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func formatValue(value any) string {
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(bs)
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "$"
+	}
+	return path
+}
@@ -0,0 +1,150 @@
+package boolexp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hgiasac/hasura-utils/v2/gql"
+	"github.com/hgiasac/hasura-utils/v2/types"
+	"gotest.tools/v3/assert"
+)
+
+type testAuthor struct {
+	ID     int    `json:"id"`
+	Banned bool   `json:"banned"`
+	Email  string `json:"email"`
+}
+
+type testPost struct {
+	ID        int            `json:"id"`
+	Title     string         `json:"title"`
+	AuthorID  int            `json:"author_id"`
+	Tags      []string       `json:"tags"`
+	Metadata  map[string]any `json:"metadata"`
+	Due       types.Date     `json:"due"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt *time.Time     `json:"deleted_at,omitempty"`
+	Author    *testAuthor    `json:"author"`
+	Comments  []struct {
+		UserID int    `json:"user_id"`
+		Body   string `json:"body"`
+	} `json:"comments"`
+}
+
+func TestEvaluate(t *testing.T) {
+	post := testPost{
+		ID:        1,
+		Title:     "50% off_sale",
+		AuthorID:  10,
+		Tags:      []string{"go", "hasura"},
+		Metadata:  map[string]any{"tier": "gold", "limits": map[string]any{"posts": 5}},
+		Due:       types.Date{Year: 2024, Month: 5, Day: 2},
+		CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Author:    &testAuthor{ID: 10, Email: "foo@example.com"},
+	}
+	post.Comments = append(post.Comments, struct {
+		UserID int    `json:"user_id"`
+		Body   string `json:"body"`
+	}{UserID: 20, Body: "nice"})
+	session := gql.NewSessionVariables(map[string]string{
+		"X-Hasura-User-Id":     "10",
+		"X-Hasura-Allowed-Ids": "{1,3}",
+		"X-Hasura-Titles":      `{"50% off_sale","a,b"}`,
+	})
+
+	testCases := []struct {
+		Name       string
+		Expression any
+		Failure    string
+	}{
+		{Name: "empty", Expression: Expression{}},
+		{Name: "eq_session", Expression: Field("author_id").Eq("X-Hasura-User-Id")},
+		{Name: "neq_session", Expression: Field("author_id").Neq("x-hasura-user-id"), Failure: `author_id: _neq 10 doesn't match "10"`},
+		{Name: "in", Expression: Field("id").In(1, 2)},
+		{Name: "nin", Expression: Field("id").Nin(1, 2), Failure: `id: _nin 1 doesn't match [1,2]`},
+		{Name: "gt_date", Expression: And(Field("due").Gt(types.Date{Year: 2024, Month: 5, Day: 1}), Field("created_at").Lt("2024-05-01T11:30:00+01:00"))},
+		{Name: "is_null", Expression: And(Field("deleted_at").IsNull(true), Field("title").IsNull(false))},
+		{Name: "like_escaped", Expression: Field("title").Like(Prefix("50% off_"))},
+		{Name: "like_wildcard", Expression: Field("title").Ilike("50_ OFF%")},
+		{Name: "like_literal", Expression: Field("title").Like(Prefix("50%_off")), Failure: `title: _like "50% off_sale" doesn't match "50\\%\\_off%"`},
+		{Name: "similar", Expression: Field("title").Similar("50% (off|on)\\_sale")},
+		{Name: "similar_posix_class", Expression: Field("title").Similar("[[:digit:]]+[[:punct:]] [[:alpha:]_]+")},
+		{Name: "similar_bracket_literal", Expression: And(Field("title").Similar("[]5]0%"), Field("title").Similar("[^]a]0%"))},
+		{Name: "similar_class_failure", Expression: Field("title").Similar("[[:alpha:]]%"), Failure: `title: _similar "50% off_sale" doesn't match "[[:alpha:]]%"`},
+		{Name: "regex", Expression: And(Field("title").Iregex("OFF_S"), Field("title").Nregex("^off"))},
+		{Name: "jsonb", Expression: And(Field("metadata").Contains(map[string]any{"limits": map[string]any{"posts": 5}}), Field("metadata").HasKeysAll("tier", "limits"), Not(Field("metadata").HasKey("admin")))},
+		{Name: "array", Expression: And(Field("tags").Contains([]string{"hasura"}), Field("tags").ContainedIn([]string{"go", "hasura", "rust"}))},
+		{Name: "array_session", Expression: Field("id").Compare(OpIn, "X-Hasura-Allowed-Ids")},
+		{Name: "array_session_quoted", Expression: Field("title").Compare(OpIn, "X-Hasura-Titles")},
+		{Name: "array_session_failure", Expression: Field("author_id").Compare(OpIn, "X-Hasura-Allowed-Ids"), Failure: `author_id: _in 10 doesn't match "{1,3}"`},
+		{Name: "object_relationship", Expression: Relationship("author", Field("id").Eq("X-Hasura-User-Id"), Field("banned").Eq(false))},
+		{Name: "array_relationship", Expression: Relationship("comments", Field("user_id").Eq(10)), Failure: `comments: none of 1 related rows matched (comments[0].user_id: _eq 20 doesn't match 10)`},
+		{Name: "column_comparison", Expression: Relationship("author", Field("id").Compare(OpCeq, []string{"$", "author_id"}))},
+		{
+			Name:       "or",
+			Expression: Or(Field("id").Eq(2), Relationship("author", Field("banned").Eq(true))),
+			Failure:    `_or none of 2 expressions matched (_or[0].id: _eq 1 doesn't match 2; _or[1].author.banned: _eq false doesn't match true)`,
+		},
+		{Name: "not", Expression: Not(Field("id").Eq(1)), Failure: `_not the negated expression matched`},
+		{Name: "json_permission", Expression: `{"_and": [{"author_id": {"_eq": "X-Hasura-User-Id"}}, {"author": {"email": {"_ilike": "%@EXAMPLE.com"}}}]}`},
+		{Name: "missing_field", Expression: Field("status").Eq("draft"), Failure: `status: _eq null doesn't match "draft"`},
+		{Name: "omitted_null", Expression: Field("deleted_at").IsNull(true)},
+		{Name: "omitted_not_null", Expression: Field("deleted_at").IsNull(false), Failure: `deleted_at: _is_null null doesn't match false`},
+		{Name: "empty_field_expression", Expression: `{"title": {}, "deleted_at": {}, "author": {}}`},
+		{Name: "missing_relationship", Expression: Relationship("editor", Field("id").Eq(1)), Failure: `editor: the related row doesn't exist in the row`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			result, err := Evaluate(tc.Expression, post, session)
+			assert.NilError(t, err)
+			if tc.Failure == "" {
+				assert.Assert(t, result.Matched, result.Failure)
+				return
+			}
+			assert.Assert(t, !result.Matched)
+			assert.Equal(t, tc.Failure, result.Failure.String())
+		})
+	}
+}
+
+func TestEvaluate_Errors(t *testing.T) {
+	row := map[string]any{"id": 1}
+	_, err := Evaluate(Field("id").Eq("X-Hasura-User-Id"), row, gql.SessionVariables{})
+	assert.ErrorIs(t, err, gql.ErrSessionVariableNotFound)
+	_, err = Evaluate(Exists("public", "users", nil), row, nil)
+	assert.ErrorContains(t, err, "_exists of unrelated tables isn't supported")
+	_, err = Evaluate(Field("id").Eq(nil), row, nil)
+	assert.ErrorContains(t, err, "id: _eq: unexpected null argument")
+	_, err = Evaluate(`{"_foo": {}}`, row, nil)
+	assert.ErrorContains(t, err, "unsupported operator _foo")
+	_, err = Evaluate(Field("title").Similar("[[=a=]]%"), map[string]any{"title": "a"}, nil)
+	assert.ErrorContains(t, err, "unsupported bracket element [=a=]")
+	_, err = Evaluate(Expression{}, []int{1}, nil)
+	assert.ErrorContains(t, err, "row must be an object")
+}
+
+func TestEvaluate_Timestamps(t *testing.T) {
+	row := map[string]any{
+		"title":      "2024-01-01T00:00:00Z",
+		"created_at": "2024-05-01T10:00:00Z",
+	}
+	for _, tc := range []struct {
+		Name       string
+		Expression any
+		Matched    bool
+	}{
+		{Name: "text_eq_other_layout", Expression: Field("title").Eq("2024-01-01"), Matched: false},
+		{Name: "text_in_other_layout", Expression: Field("title").In("2024-01-01"), Matched: false},
+		{Name: "text_eq", Expression: Field("title").Eq("2024-01-01T00:00:00Z"), Matched: true},
+		{Name: "eq_same_layout", Expression: Field("created_at").Eq("2024-05-01T11:00:00+01:00"), Matched: true},
+		{Name: "gt_other_layout", Expression: Field("created_at").Gt("2024-05-01"), Matched: true},
+		{Name: "lt_other_layout", Expression: Field("created_at").Lt("2024-05-01 10:30:00"), Matched: true},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			result, err := Evaluate(tc.Expression, row, nil)
+			assert.NilError(t, err)
+			assert.Equal(t, tc.Matched, result.Matched, result.Failure)
+		})
+	}
+}
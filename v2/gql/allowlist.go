@@ -0,0 +1,225 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	meter = otel.Meter("github.com/hgiasac/hasura-utils/v2/gql")
+	// allowlistViolations counts operations that aren't in the allow-list of the client
+	allowlistViolations, _ = meter.Int64Counter("hasura.client.allowlist.violations",
+		metric.WithDescription("The number of operations that aren't in the operation allow-list"),
+		metric.WithUnit("{operation}"),
+	)
+)
+
+// ErrOperationNotAllowed is returned when the operation isn't in the allow-list of the client
+var ErrOperationNotAllowed = errors.New("operation is not in the allow-list")
+
+// ErrAllowlistSectionRequired is returned when the metadata of LoadOperationAllowlist has no allowlist field
+var ErrAllowlistSectionRequired = errors.New("the metadata has no allowlist, use LoadAllCollections to load every query collection")
+
+// AllowlistError represents an operation that is rejected by the allow-list before the request is sent
+type AllowlistError struct {
+	OperationName string
+	// Hash the canonical hash of the rejected document. It's empty if the document is invalid
+	Hash string
+}
+
+// Error implements the error interface
+func (ae *AllowlistError) Error() string {
+	if ae.OperationName == "" {
+		return fmt.Sprintf("%s: hash %s", ErrOperationNotAllowed, ae.Hash)
+	}
+	return fmt.Sprintf("%s <%s>: hash %s", ErrOperationNotAllowed, ae.OperationName, ae.Hash)
+}
+
+// Unwrap returns ErrOperationNotAllowed
+func (ae *AllowlistError) Unwrap() error {
+	return ErrOperationNotAllowed
+}
+
+// OperationAllowlist the set of GraphQL documents that the client is allowed to send.
+// Documents are matched by the canonical hash, so formatting and field order don't matter
+type OperationAllowlist struct {
+	mu     sync.RWMutex
+	hashes map[string]string
+}
+
+// NewOperationAllowlist creates an in-memory allow-list of the collected queries
+func NewOperationAllowlist(queries ...CollectedQuery) (*OperationAllowlist, error) {
+	al := &OperationAllowlist{
+		hashes: map[string]string{},
+	}
+	for _, q := range queries {
+		if err := al.Add(q.Name, q.Query); err != nil {
+			return nil, err
+		}
+	}
+	return al, nil
+}
+
+type allowlistLoadOptions struct {
+	allCollections bool
+}
+
+// AllowlistLoadOption the optional setting function of LoadOperationAllowlist
+type AllowlistLoadOption func(*allowlistLoadOptions)
+
+// LoadAllCollections loads queries of every query collection in the metadata, even if the metadata has no allowlist
+func LoadAllCollections() AllowlistLoadOption {
+	return func(opts *allowlistLoadOptions) {
+		opts.allCollections = true
+	}
+}
+
+// LoadOperationAllowlist loads the allow-list from the JSON of Hasura metadata export.
+// The JSON can be the metadata object, the export_metadata response of version 2, or the array of query collections.
+// Only queries of allow-listed collections of the metadata are loaded. The metadata must have the allowlist field
+// unless LoadAllCollections is set, so that a metadata export without the allow-list doesn't allow every collection
+func LoadOperationAllowlist(data []byte, options ...AllowlistLoadOption) (*OperationAllowlist, error) {
+	var opts allowlistLoadOptions
+	for _, apply := range options {
+		apply(&opts)
+	}
+
+	var collections []QueryCollection
+	if err := json.Unmarshal(data, &collections); err == nil {
+		return newOperationAllowlistFromCollections(collections, nil)
+	}
+
+	var metadata struct {
+		exportedMetadata
+		Metadata *exportedMetadata `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode query collections: %w", err)
+	}
+	exported := metadata.exportedMetadata
+	if metadata.Metadata != nil {
+		exported = *metadata.Metadata
+	}
+	if opts.allCollections {
+		return newOperationAllowlistFromCollections(exported.QueryCollections, nil)
+	}
+	if exported.Allowlist == nil {
+		return nil, ErrAllowlistSectionRequired
+	}
+
+	return newOperationAllowlistFromCollections(exported.QueryCollections, exported.Allowlist)
+}
+
+// newOperationAllowlistFromCollections loads queries of the collections. A nil allowlist loads every collection
+func newOperationAllowlistFromCollections(collections []QueryCollection, allowlist []AllowlistEntry) (*OperationAllowlist, error) {
+	al, _ := NewOperationAllowlist()
+	for _, collection := range collections {
+		if allowlist != nil && !slices.ContainsFunc(allowlist, func(entry AllowlistEntry) bool {
+			return entry.Collection == collection.Name
+		}) {
+			continue
+		}
+		for _, q := range collection.Definition.Queries {
+			if err := al.Add(q.Name, q.Query); err != nil {
+				return nil, fmt.Errorf("collection %s: %w", collection.Name, err)
+			}
+		}
+	}
+	return al, nil
+}
+
+// Add adds the GraphQL document to the allow-list
+func (al *OperationAllowlist) Add(name string, query string) error {
+	hash, err := CanonicalQueryHash(query)
+	if err != nil {
+		return fmt.Errorf("invalid document of query %s: %w", name, err)
+	}
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.hashes[hash] = name
+	return nil
+}
+
+// Len returns the number of allowed documents
+func (al *OperationAllowlist) Len() int {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+	return len(al.hashes)
+}
+
+// Check returns the canonical hash of the document and whether the document is allowed
+func (al *OperationAllowlist) Check(query string) (string, bool) {
+	hash, err := CanonicalQueryHash(query)
+	if err != nil {
+		return "", false
+	}
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+	_, ok := al.hashes[hash]
+	return hash, ok
+}
+
+type allowlistOptions struct {
+	allowlist  *OperationAllowlist
+	reportOnly bool
+}
+
+// WithOperationAllowlist rejects operations that aren't in the allow-list with an AllowlistError
+// before requests are sent
+func WithOperationAllowlist(allowlist *OperationAllowlist) Option {
+	return func(opts *options) {
+		opts.allowlist.allowlist = allowlist
+	}
+}
+
+// WithAllowlistReportOnly logs operations that aren't in the allow-list and counts them
+// in the hasura.client.allowlist.violations metric instead of rejecting them
+func WithAllowlistReportOnly() Option {
+	return func(opts *options) {
+		opts.allowlist.reportOnly = true
+	}
+}
+
+// checkAllowlist validates the document against the allow-list of the client
+func (c *HasuraClient) checkAllowlist(ctx context.Context, op operation, query string, operationName string) error {
+	if c.allowlist.allowlist == nil {
+		return nil
+	}
+	hash, ok := c.allowlist.allowlist.Check(query)
+	if ok {
+		return nil
+	}
+
+	allowlistViolations.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("operation_name", operationName),
+		attribute.Bool("report_only", c.allowlist.reportOnly),
+	))
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("allowlist_violation", true))
+	if !c.allowlist.reportOnly {
+		return &AllowlistError{
+			OperationName: operationName,
+			Hash:          hash,
+		}
+	}
+
+	logger := slog.Default()
+	if c.logger != nil {
+		logger = c.logger.logger
+	}
+	logger.LogAttrs(ctx, slog.LevelWarn, "hasura operation not in allow-list",
+		slog.String("operation", op.method),
+		slog.String("operation_name", operationName),
+		slog.String("hash", hash),
+	)
+	return nil
+}
@@ -0,0 +1,126 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+	"gotest.tools/v3/assert"
+)
+
+func TestLoadOperationAllowlist(t *testing.T) {
+	metadata := `{
+		"resource_version": 2,
+		"metadata": {
+			"query_collections": [
+				{"name": "allowed", "definition": {"queries": [{"name": "GetUsers", "query": "query GetUsers { users { id name } }"}]}},
+				{"name": "draft", "definition": {"queries": [{"name": "GetPosts", "query": "query GetPosts { posts { id } }"}]}}
+			],
+			"allowlist": [{"collection": "allowed"}]
+		}
+	}`
+	allowlist, err := LoadOperationAllowlist([]byte(metadata))
+	assert.NilError(t, err)
+	assert.Equal(t, 1, allowlist.Len())
+	_, ok := allowlist.Check("query GetUsers {\n  users {\n    name\n    id\n  }\n}")
+	assert.Assert(t, ok)
+	_, ok = allowlist.Check("query GetPosts { posts { id } }")
+	assert.Assert(t, !ok)
+
+	collections := `[{"name": "draft", "definition": {"queries": [{"name": "GetPosts", "query": "query GetPosts { posts { id } }"}]}}]`
+	allowlist, err = LoadOperationAllowlist([]byte(collections))
+	assert.NilError(t, err)
+	_, ok = allowlist.Check("query GetPosts { posts { id } }")
+	assert.Assert(t, ok)
+
+	_, err = LoadOperationAllowlist([]byte(`[{"name": "invalid", "definition": {"queries": [{"name": "Bad", "query": "query { users { id }"}]}}]`))
+	assert.ErrorContains(t, err, "collection invalid: invalid document of query Bad")
+
+	// metadata without the allowlist doesn't allow every collection by default
+	noAllowlist := `{"query_collections": [{"name": "draft", "definition": {"queries": [{"name": "GetPosts", "query": "query GetPosts { posts { id } }"}]}}]}`
+	_, err = LoadOperationAllowlist([]byte(noAllowlist))
+	assert.ErrorIs(t, err, ErrAllowlistSectionRequired)
+	_, err = LoadOperationAllowlist([]byte(`{"resource_version": 2, "metadata": {}}`))
+	assert.ErrorIs(t, err, ErrAllowlistSectionRequired)
+
+	allowlist, err = LoadOperationAllowlist([]byte(noAllowlist), LoadAllCollections())
+	assert.NilError(t, err)
+	assert.Equal(t, 1, allowlist.Len())
+
+	allowlist, err = LoadOperationAllowlist([]byte(`{"query_collections": [{"name": "draft", "definition": {"queries": [{"name": "GetPosts", "query": "query GetPosts { posts { id } }"}]}}], "allowlist": []}`))
+	assert.NilError(t, err)
+	assert.Equal(t, 0, allowlist.Len())
+}
+
+func TestHasuraClient_OperationAllowlist(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"data": {"users": [{"id": 1, "name": "foo"}]}}`))
+	}))
+	defer server.Close()
+
+	type usersQuery struct {
+		Users []struct {
+			ID   int    `graphql:"id"`
+			Name string `graphql:"name"`
+		} `graphql:"users"`
+	}
+	collector := NewQueryCollector()
+	assert.NilError(t, collector.AddQuery("GetUsers", &usersQuery{}, nil, graphql.OperationName("GetUsers")))
+	allowlist, err := NewOperationAllowlist(collector.Queries()...)
+	assert.NilError(t, err)
+
+	client := NewAdminClient(server.URL, "secret", WithOperationAllowlist(allowlist))
+	var result usersQuery
+	assert.NilError(t, client.Query(context.Background(), &result, nil, graphql.OperationName("GetUsers")))
+	assert.Equal(t, "foo", result.Users[0].Name)
+
+	// field order doesn't matter
+	_, err = client.ExecRaw(context.Background(), "query GetUsers { users { name id } }", nil)
+	assert.NilError(t, err)
+
+	_, err = client.ExecRaw(context.Background(), "query GetUsers { users { id name email } }", nil, graphql.OperationName("GetUsers"))
+	assert.Assert(t, errors.Is(err, ErrOperationNotAllowed))
+	var allowlistErr *AllowlistError
+	assert.Assert(t, errors.As(err, &allowlistErr))
+	assert.Equal(t, "GetUsers", allowlistErr.OperationName)
+	assert.Equal(t, int32(2), requests.Load())
+
+	// derived clients inherit the allow-list
+	userClient, err := client.AsRole("user", "1")
+	assert.NilError(t, err)
+	_, err = userClient.ExecRaw(context.Background(), "query { posts { id } }", nil)
+	assert.Assert(t, errors.Is(err, ErrOperationNotAllowed))
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestHasuraClient_OperationAllowlistReportOnly(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"data": {"posts": []}}`))
+	}))
+	defer server.Close()
+
+	allowlist, err := NewOperationAllowlist()
+	assert.NilError(t, err)
+	var buf bytes.Buffer
+	client := NewAdminClient(server.URL, "secret",
+		WithOperationAllowlist(allowlist),
+		WithAllowlistReportOnly(),
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+	)
+
+	_, err = client.ExecRaw(context.Background(), "query GetPosts { posts { id } }", nil, graphql.OperationName("GetPosts"))
+	assert.NilError(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Assert(t, bytes.Contains(buf.Bytes(), []byte(`"msg":"hasura operation not in allow-list"`)))
+	assert.Assert(t, bytes.Contains(buf.Bytes(), []byte(`"operation_name":"GetPosts"`)))
+}
@@ -0,0 +1,304 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+	"github.com/hgiasac/hasura-utils/v2/types"
+)
+
+type asyncActionOptions struct {
+	initialInterval time.Duration
+	maxInterval     time.Duration
+	multiplier      float64
+	subscribe       bool
+	outputSelection *string
+}
+
+var defaultAsyncActionOptions = asyncActionOptions{
+	initialInterval: 500 * time.Millisecond,
+	maxInterval:     10 * time.Second,
+	multiplier:      2,
+}
+
+// AsyncActionOption the optional setting function of AwaitAsyncAction
+type AsyncActionOption func(*asyncActionOptions)
+
+// WithPollInterval sets the initial and maximum interval of the exponential polling backoff
+func WithPollInterval(initial time.Duration, max time.Duration) AsyncActionOption {
+	return func(opts *asyncActionOptions) {
+		opts.initialInterval = initial
+		opts.maxInterval = max
+	}
+}
+
+// WithPollMultiplier sets the multiplier of the exponential polling backoff
+func WithPollMultiplier(multiplier float64) AsyncActionOption {
+	return func(opts *asyncActionOptions) {
+		opts.multiplier = multiplier
+	}
+}
+
+// WithAsyncSubscription waits for the result with a GraphQL subscription instead of polling
+func WithAsyncSubscription() AsyncActionOption {
+	return func(opts *asyncActionOptions) {
+		opts.subscribe = true
+	}
+}
+
+// WithOutputSelection sets the selection set of the output field, e.g. `{ id name }`.
+// By default the selection set is derived from the struct fields of the output type
+func WithOutputSelection(selection string) AsyncActionOption {
+	return func(opts *asyncActionOptions) {
+		opts.outputSelection = &selection
+	}
+}
+
+type asyncActionResult struct {
+	ID        string          `json:"id"`
+	CreatedAt string          `json:"created_at"`
+	Errors    json.RawMessage `json:"errors"`
+	Output    json.RawMessage `json:"output"`
+}
+
+func (aar asyncActionResult) isCompleted() bool {
+	return !isNullJSON(aar.Errors) || !isNullJSON(aar.Output)
+}
+
+type asyncActionError struct {
+	Message    string         `json:"message"`
+	Error      string         `json:"error"`
+	Code       string         `json:"code"`
+	Path       string         `json:"path"`
+	Extensions map[string]any `json:"extensions"`
+}
+
+// AwaitAsyncAction waits until the async action of the id finishes, then decodes the output into T.
+// The action result is queried with the session variables of the client,
+// so use the client that executed the action mutation
+func AwaitAsyncAction[T any](ctx context.Context, c *HasuraClient, actionName string, id string, options ...AsyncActionOption) (T, error) {
+	var output T
+	opts := defaultAsyncActionOptions
+	for _, apply := range options {
+		apply(&opts)
+	}
+
+	selection := ""
+	if opts.outputSelection != nil {
+		selection = *opts.outputSelection
+	} else {
+		s, err := buildOutputSelection(output)
+		if err != nil {
+			return output, err
+		}
+		selection = s
+	}
+
+	fields := fmt.Sprintf("result: %s(id: $id) { id created_at errors output %s }", actionName, selection)
+	variables := map[string]any{
+		"id": id,
+	}
+
+	var result *asyncActionResult
+	var err error
+	if opts.subscribe {
+		result, err = c.subscribeAsyncAction(ctx, fields, variables)
+	} else {
+		result, err = c.pollAsyncAction(ctx, fields, variables, opts)
+	}
+	if err != nil {
+		return output, err
+	}
+
+	if !isNullJSON(result.Errors) {
+		return output, decodeAsyncActionErrors(result.Errors, actionName, id)
+	}
+
+	// struct outputs are decoded with graphql tags, scalar outputs such as jsonb are decoded as JSON
+	decode := json.Unmarshal
+	if selection != "" {
+		decode = graphql.UnmarshalGraphQL
+	}
+	if err := decode(result.Output, &output); err != nil {
+		return output, types.ErrDecodeJSON(err, map[string]any{
+			"action_name": actionName,
+			"action_id":   id,
+		})
+	}
+
+	return output, nil
+}
+
+func (c *HasuraClient) pollAsyncAction(ctx context.Context, fields string, variables map[string]any, opts asyncActionOptions) (*asyncActionResult, error) {
+	query := fmt.Sprintf("query AwaitAsyncAction($id: uuid!) { %s }", fields)
+	interval := opts.initialInterval
+	for {
+		var data struct {
+			Result *asyncActionResult `json:"result"`
+		}
+		bs, err := c.ExecRaw(ctx, query, variables, graphql.OperationName("AwaitAsyncAction"))
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(bs, &data); err != nil {
+			return nil, types.ErrDecodeJSON(err, nil)
+		}
+		if data.Result != nil && data.Result.isCompleted() {
+			return data.Result, nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		interval = time.Duration(float64(interval) * opts.multiplier)
+		if opts.maxInterval > 0 && interval > opts.maxInterval {
+			interval = opts.maxInterval
+		}
+	}
+}
+
+// subscribeAsyncAction subscribes to the action result. The session variables, the tenant
+// and the request id are resolved in the same way as HTTP requests
+func (c *HasuraClient) subscribeAsyncAction(ctx context.Context, fields string, variables map[string]any) (*asyncActionResult, error) {
+	query := fmt.Sprintf("subscription AwaitAsyncAction($id: uuid!) { %s }", fields)
+	op := operation{
+		method:        "AwaitAsyncAction",
+		operationType: operationSubscription,
+		query:         query,
+		variables:     variables,
+	}
+	ctx, req, err := c.prepareRequest(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkReadOnly(op, query); err != nil {
+		return nil, err
+	}
+	if err := c.checkAllowlist(ctx, op, query, "AwaitAsyncAction"); err != nil {
+		return nil, err
+	}
+
+	headers := http.Header{}
+	for k, v := range req.sessionVariables {
+		headers.Set(k, v)
+	}
+
+	sc := graphql.NewSubscriptionClient(toWebsocketURL(req.endpoint)).
+		WithConnectionParams(map[string]any{
+			"headers": req.sessionVariables.ToStringMap(),
+		}).
+		WithWebSocketOptions(graphql.WebsocketOptions{
+			HTTPHeader: headers,
+		}).
+		WithLog(func(args ...interface{}) {})
+	defer sc.Close()
+
+	resultChan := make(chan *asyncActionResult, 1)
+	errChan := make(chan error, 2)
+	_, err = sc.Exec(query, variables, func(message []byte, err error) error {
+		if err != nil {
+			errChan <- err
+			return graphql.ErrSubscriptionStopped
+		}
+
+		var data struct {
+			Result *asyncActionResult `json:"result"`
+		}
+		if err := json.Unmarshal(message, &data); err != nil {
+			errChan <- types.ErrDecodeJSON(err, nil)
+			return graphql.ErrSubscriptionStopped
+		}
+		if data.Result == nil || !data.Result.isCompleted() {
+			return nil
+		}
+		resultChan <- data.Result
+		return graphql.ErrSubscriptionStopped
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		if err := sc.Run(); err != nil {
+			errChan <- err
+		}
+	}()
+
+	select {
+	case result := <-resultChan:
+		return result, nil
+	case err := <-errChan:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func decodeAsyncActionErrors(raw json.RawMessage, actionName string, id string) error {
+	var actionErr asyncActionError
+	if err := json.Unmarshal(raw, &actionErr); err != nil {
+		return types.ErrUnknown(errors.New(string(raw)), map[string]any{
+			"action_name": actionName,
+			"action_id":   id,
+		})
+	}
+
+	gqlErr := graphql.Error{
+		Message:    actionErr.Message,
+		Extensions: actionErr.Extensions,
+	}
+	if gqlErr.Message == "" {
+		gqlErr.Message = actionErr.Error
+	}
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = map[string]any{}
+	}
+	if actionErr.Code != "" {
+		gqlErr.Extensions["code"] = actionErr.Code
+	}
+	if actionErr.Path != "" {
+		gqlErr.Extensions["path"] = actionErr.Path
+	}
+
+	return types.ToRouterError(gqlErr, map[string]any{
+		"action_name": actionName,
+		"action_id":   id,
+	})
+}
+
+// buildOutputSelection derives the selection set from struct types, scalar types don't need a selection set
+func buildOutputSelection(output any) (string, error) {
+	t := reflect.TypeOf(output)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) || t == reflect.TypeOf(types.Date{}) {
+		return "", nil
+	}
+
+	return graphql.ConstructQuery(reflect.New(t).Interface(), nil)
+}
+
+func toWebsocketURL(endpoint string) string {
+	if strings.HasPrefix(endpoint, "https://") {
+		return "wss://" + strings.TrimPrefix(endpoint, "https://")
+	}
+	return "ws://" + strings.TrimPrefix(endpoint, "http://")
+}
+
+func isNullJSON(raw json.RawMessage) bool {
+	value := strings.TrimSpace(string(raw))
+	return value == "" || value == "null"
+}
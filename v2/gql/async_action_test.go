@@ -0,0 +1,239 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+	"github.com/hgiasac/hasura-router/go/types"
+	"gotest.tools/v3/assert"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+func TestAwaitAsyncAction(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Check(t, r.Header.Get(XHasuraRole) == "user")
+		var body struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		assert.Check(t, json.NewDecoder(r.Body).Decode(&body))
+
+		switch body.Variables["id"] {
+		case "failed":
+			_, _ = w.Write([]byte(`{"data": {"result": {"id": "failed", "errors": {"message": "boom", "extensions": {"code": "unexpected"}}, "output": null}}}`))
+		default:
+			assert.Check(t, strings.Contains(body.Query, "output {id,name}"), body.Query)
+			if atomic.AddInt32(&calls, 1) < 3 {
+				_, _ = w.Write([]byte(`{"data": {"result": {"id": "1", "errors": null, "output": null}}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data": {"result": {"id": "1", "errors": null, "output": {"id": 1, "name": "foo"}}}}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewAdminClient(server.URL+"/v1/graphql", "secret").AsRole("user", "1")
+	assert.NilError(t, err)
+
+	type output struct {
+		ID   int
+		Name string
+	}
+	result, err := AwaitAsyncAction[output](context.Background(), client, "createUser", "1", WithPollInterval(time.Millisecond, 5*time.Millisecond))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, output{ID: 1, Name: "foo"}, result)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	_, err = AwaitAsyncAction[output](context.Background(), client, "createUser", "failed")
+	var actionErr types.Error
+	assert.Assert(t, errors.As(err, &actionErr))
+	assert.Equal(t, "boom", actionErr.Message)
+	assert.Equal(t, "unexpected", actionErr.Extensions["code"])
+	assert.Equal(t, "failed", actionErr.Extensions["action_id"])
+}
+
+// newAsyncActionSubscriptionServer serves async action subscriptions with the subscriptions-transport-ws protocol.
+// The connect function receives the upgrade request and the headers of the connection params
+func newAsyncActionSubscriptionServer(t *testing.T, connect func(r *http.Request, headers map[string]string)) *httptest.Server {
+	type operationMessage struct {
+		ID      string          `json:"id,omitempty"`
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload,omitempty"`
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{Subprotocols: []string{"graphql-ws"}})
+		if !assert.Check(t, err) {
+			return
+		}
+		defer conn.CloseNow()
+
+		ctx := r.Context()
+		var msg operationMessage
+		if !assert.Check(t, wsjson.Read(ctx, conn, &msg)) {
+			return
+		}
+		assert.Check(t, msg.Type == "connection_init", msg.Type)
+		var params struct {
+			Headers map[string]string `json:"headers"`
+		}
+		assert.Check(t, json.Unmarshal(msg.Payload, &params))
+		connect(r, params.Headers)
+		if !assert.Check(t, wsjson.Write(ctx, conn, operationMessage{Type: "connection_ack"})) {
+			return
+		}
+
+		if !assert.Check(t, wsjson.Read(ctx, conn, &msg)) {
+			return
+		}
+		assert.Check(t, msg.Type == "start", msg.Type)
+		var payload struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		assert.Check(t, json.Unmarshal(msg.Payload, &payload))
+		assert.Check(t, strings.HasPrefix(payload.Query, "subscription AwaitAsyncAction"), payload.Query)
+
+		var frames []string
+		switch payload.Variables["id"] {
+		case "denied":
+			frames = []string{`{"type": "error", "payload": [{"message": "permission denied", "extensions": {"code": "access-denied"}}]}`}
+		default:
+			frames = []string{
+				`{"type": "data", "payload": {"data": {"result": {"id": "1", "errors": null, "output": null}}}}`,
+				`{"type": "data", "payload": {"data": {"result": {"id": "1", "errors": null, "output": {"id": 1, "name": "foo"}}}}}`,
+			}
+		}
+		for _, frame := range frames {
+			var reply operationMessage
+			assert.Check(t, json.Unmarshal([]byte(frame), &reply))
+			reply.ID = msg.ID
+			if !assert.Check(t, wsjson.Write(ctx, conn, reply)) {
+				return
+			}
+		}
+
+		// wait until the client stops the subscription
+		for wsjson.Read(ctx, conn, &msg) == nil {
+		}
+	}))
+}
+
+func TestAwaitAsyncAction_Subscription(t *testing.T) {
+	server := newAsyncActionSubscriptionServer(t, func(r *http.Request, headers map[string]string) {
+		assert.Check(t, r.Header.Get(XHasuraRole) == "user")
+		assert.Check(t, headers[XHasuraRole] == "user", headers)
+	})
+	defer server.Close()
+
+	client, err := NewAdminClient(server.URL+"/v1/graphql", "secret").AsRole("user", "1")
+	assert.NilError(t, err)
+
+	type output struct {
+		ID   int
+		Name string
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := AwaitAsyncAction[output](ctx, client, "createUser", "1", WithAsyncSubscription())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, output{ID: 1, Name: "foo"}, result)
+
+	_, err = AwaitAsyncAction[output](ctx, client, "createUser", "denied", WithAsyncSubscription())
+	var gqlErrs graphql.Errors
+	assert.Assert(t, errors.As(err, &gqlErrs), err)
+	assert.Equal(t, "permission denied", gqlErrs[0].Message)
+	assert.Equal(t, "access-denied", gqlErrs[0].Extensions["code"])
+}
+
+func TestAwaitAsyncAction_SubscriptionTenant(t *testing.T) {
+	var mu sync.Mutex
+	var connections []string
+	record := func(name string) func(r *http.Request, headers map[string]string) {
+		return func(r *http.Request, headers map[string]string) {
+			mu.Lock()
+			defer mu.Unlock()
+			connections = append(connections, name)
+			assert.Check(t, r.Header.Get(XHasuraTenantID) == "dedicated", r.Header)
+			assert.Check(t, headers[XHasuraTenantID] == "dedicated", headers)
+			assert.Check(t, headers[XHasuraAdminSecret] == "dedicated-secret", headers)
+			assert.Check(t, headers[XRequestId] == "request-1", headers)
+		}
+	}
+	server := newAsyncActionSubscriptionServer(t, record("shared"))
+	defer server.Close()
+	tenantServer := newAsyncActionSubscriptionServer(t, record("dedicated"))
+	defer tenantServer.Close()
+
+	client, err := NewAdminClient(server.URL+"/v1/graphql", "secret",
+		WithTenantResolver(ContextTenantResolver),
+		WithTenantConfigResolver(func(ctx context.Context, tenantID string) (*TenantConfig, error) {
+			return &TenantConfig{Endpoint: tenantServer.URL + "/v1/graphql", AdminSecret: "dedicated-secret"}, nil
+		}),
+	).AsRole("user", "1")
+	assert.NilError(t, err)
+
+	type output struct {
+		ID   int
+		Name string
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = AwaitAsyncAction[output](ctx, client, "createUser", "1", WithAsyncSubscription())
+	assert.ErrorIs(t, err, ErrTenantRequired)
+
+	tenantCtx := NewRequestIDContext(NewTenantContext(ctx, "dedicated"), "request-1")
+	result, err := AwaitAsyncAction[output](tenantCtx, client, "createUser", "1", WithAsyncSubscription())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, output{ID: 1, Name: "foo"}, result)
+	assert.DeepEqual(t, []string{"dedicated"}, connections)
+
+	// the error of the client is returned before connecting
+	invalidClient := NewHasuraClient(server.URL+"/v1/graphql", WithBackendOnlyRole("editor", ""))
+	_, err = AwaitAsyncAction[output](ctx, invalidClient, "createUser", "1", WithAsyncSubscription())
+	assert.ErrorIs(t, err, invalidClient.Err())
+	assert.DeepEqual(t, []string{"dedicated"}, connections)
+}
+
+func TestAwaitAsyncAction_SubscriptionAllowlist(t *testing.T) {
+	var connections atomic.Int32
+	server := newAsyncActionSubscriptionServer(t, func(r *http.Request, headers map[string]string) {
+		connections.Add(1)
+	})
+	defer server.Close()
+
+	type output struct {
+		ID   int
+		Name string
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	allowlist, err := NewOperationAllowlist()
+	assert.NilError(t, err)
+	client := NewAdminClient(server.URL+"/v1/graphql", "secret", WithOperationAllowlist(allowlist), WithReadOnly())
+	_, err = AwaitAsyncAction[output](ctx, client, "createUser", "1", WithAsyncSubscription())
+	var allowlistErr *AllowlistError
+	assert.Assert(t, errors.As(err, &allowlistErr), err)
+	assert.Equal(t, "AwaitAsyncAction", allowlistErr.OperationName)
+	assert.Equal(t, int32(0), connections.Load())
+
+	assert.NilError(t, allowlist.Add("AwaitAsyncAction", "subscription AwaitAsyncAction($id: uuid!) { result: createUser(id: $id) { id created_at errors output { id name } } }"))
+	result, err := AwaitAsyncAction[output](ctx, client, "createUser", "1", WithAsyncSubscription())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, output{ID: 1, Name: "foo"}, result)
+	assert.Equal(t, int32(1), connections.Load())
+}
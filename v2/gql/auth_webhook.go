@@ -0,0 +1,163 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	rtypes "github.com/hgiasac/hasura-router/go/types"
+	"github.com/hgiasac/hasura-utils/v2/types"
+)
+
+const (
+	authHookCacheControl = "Cache-Control"
+	authHookExpires      = "Expires"
+)
+
+var errAuthHookRoleRequired = fmt.Errorf("%s session variable is required", XHasuraRole)
+
+// AuthenticateFunc authenticates the incoming request and returns the session variables of the user.
+// Return the Cache-Control or Expires keys in the session variables to control the cache per response
+type AuthenticateFunc func(ctx context.Context, r *http.Request) (SessionVariables, error)
+
+type authWebhookOptions struct {
+	cacheMaxAge        time.Duration
+	unauthorizedRole   string
+	maxRequestBodySize int64
+}
+
+var defaultAuthWebhookOptions = authWebhookOptions{
+	maxRequestBodySize: 1 << 20,
+}
+
+// AuthWebhookOption the optional setting function of the auth webhook handler
+type AuthWebhookOption func(*authWebhookOptions)
+
+// WithAuthCacheMaxAge sets the Cache-Control max-age of successful responses, so Hasura caches the session
+func WithAuthCacheMaxAge(maxAge time.Duration) AuthWebhookOption {
+	return func(opts *authWebhookOptions) {
+		opts.cacheMaxAge = maxAge
+	}
+}
+
+// WithUnauthorizedRole responds the role instead of 401 when the authentication fails
+func WithUnauthorizedRole(role string) AuthWebhookOption {
+	return func(opts *authWebhookOptions) {
+		opts.unauthorizedRole = role
+	}
+}
+
+// authWebhookPostBody represents the request body of the auth webhook in POST mode
+type authWebhookPostBody struct {
+	Headers map[string]string `json:"headers"`
+	Request json.RawMessage   `json:"request"`
+}
+
+type authWebhookHandler struct {
+	authenticate AuthenticateFunc
+	options      authWebhookOptions
+}
+
+// NewAuthWebhookHandler creates an http handler for the Hasura auth webhook (HASURA_GRAPHQL_AUTH_HOOK).
+// Both GET and POST modes are supported. In POST mode, the request passed to the authenticate function
+// carries the forwarded client headers and the GraphQL request as the body
+func NewAuthWebhookHandler(authenticate AuthenticateFunc, options ...AuthWebhookOption) http.Handler {
+	opts := defaultAuthWebhookOptions
+	for _, apply := range options {
+		apply(&opts)
+	}
+
+	return &authWebhookHandler{
+		authenticate: authenticate,
+		options:      opts,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface
+func (h *authWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := r
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var body authWebhookPostBody
+		if err := json.NewDecoder(io.LimitReader(r.Body, h.options.maxRequestBodySize)).Decode(&body); err != nil {
+			writeAuthWebhookError(w, http.StatusBadRequest, types.ErrDecodeJSON(err, nil))
+			return
+		}
+
+		req = r.Clone(r.Context())
+		req.Header = http.Header{}
+		for k, v := range body.Headers {
+			req.Header.Set(k, v)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body.Request))
+		req.ContentLength = int64(len(body.Request))
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeAuthWebhookError(w, http.StatusMethodNotAllowed, types.ErrBadRequest(fmt.Errorf("method %s is not allowed", r.Method), nil))
+		return
+	}
+
+	sessionVariables, err := h.authenticate(req.Context(), req)
+	if err != nil {
+		if h.options.unauthorizedRole != "" {
+			writeAuthWebhookResponse(w, map[string]string{XHasuraRole: h.options.unauthorizedRole})
+			return
+		}
+		var routerErr rtypes.Error
+		if !errors.As(err, &routerErr) {
+			routerErr = types.ErrUnauthorized(err, nil)
+		}
+		writeAuthWebhookError(w, http.StatusUnauthorized, routerErr)
+		return
+	}
+
+	if sessionVariables.GetRole() == "" {
+		writeAuthWebhookError(w, http.StatusInternalServerError, types.ErrInternal(errAuthHookRoleRequired, nil))
+		return
+	}
+
+	response := make(map[string]string)
+	for k, v := range sessionVariables {
+		switch {
+		case strings.EqualFold(k, XHasuraAdminSecret):
+		case strings.EqualFold(k, authHookCacheControl):
+			response[authHookCacheControl] = v
+		case strings.EqualFold(k, authHookExpires):
+			response[authHookExpires] = v
+		default:
+			response[k] = v
+		}
+	}
+	if _, ok := response[authHookCacheControl]; !ok && h.options.cacheMaxAge > 0 {
+		response[authHookCacheControl] = fmt.Sprintf("max-age=%d", int(h.options.cacheMaxAge.Seconds()))
+	}
+
+	writeAuthWebhookResponse(w, response)
+}
+
+func writeAuthWebhookResponse(w http.ResponseWriter, response map[string]string) {
+	if cacheControl, ok := response[authHookCacheControl]; ok {
+		w.Header().Set(authHookCacheControl, cacheControl)
+	}
+	if expires, ok := response[authHookExpires]; ok {
+		w.Header().Set(authHookExpires, expires)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func writeAuthWebhookError(w http.ResponseWriter, status int, err rtypes.Error) {
+	writeJSON(w, status, err)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
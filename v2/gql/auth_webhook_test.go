@@ -0,0 +1,95 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestAuthWebhookHandler(t *testing.T) {
+	handler := NewAuthWebhookHandler(func(ctx context.Context, r *http.Request) (SessionVariables, error) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer user":
+			return SessionVariables{XHasuraRole: "user", XHasuraUserID: "1", XHasuraAdminSecret: "leak"}, nil
+		case "Bearer norole":
+			return SessionVariables{XHasuraUserID: "1"}, nil
+		default:
+			return nil, errors.New("invalid token")
+		}
+	}, WithAuthCacheMaxAge(time.Minute))
+
+	for _, tc := range []struct {
+		Name     string
+		Request  *http.Request
+		Status   int
+		Response map[string]any
+	}{
+		{
+			Name: "get",
+			Request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("Authorization", "Bearer user")
+				return r
+			}(),
+			Status: http.StatusOK,
+			Response: map[string]any{
+				XHasuraRole:     "user",
+				XHasuraUserID:   "1",
+				"Cache-Control": "max-age=60",
+			},
+		},
+		{
+			Name:    "post",
+			Request: httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"headers": {"Authorization": "Bearer user"}, "request": {"query": "{ users { id } }"}}`)),
+			Status:  http.StatusOK,
+			Response: map[string]any{
+				XHasuraRole:     "user",
+				XHasuraUserID:   "1",
+				"Cache-Control": "max-age=60",
+			},
+		},
+		{
+			Name:    "unauthorized",
+			Request: httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"headers": {"Authorization": "Bearer invalid"}}`)),
+			Status:  http.StatusUnauthorized,
+			Response: map[string]any{
+				"code":       "unauthorized",
+				"message":    "invalid token",
+				"extensions": map[string]any{"code": "unauthorized"},
+			},
+		},
+		{
+			Name: "missing_role",
+			Request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("Authorization", "Bearer norole")
+				return r
+			}(),
+			Status: http.StatusInternalServerError,
+			Response: map[string]any{
+				"code":       "internal_error",
+				"message":    "x-hasura-role session variable is required",
+				"extensions": map[string]any{"code": "internal_error"},
+			},
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, tc.Request)
+			assert.Equal(t, tc.Status, recorder.Code)
+
+			var response map[string]any
+			bs, _ := io.ReadAll(recorder.Body)
+			assert.NilError(t, json.Unmarshal(bs, &response))
+			assert.DeepEqual(t, tc.Response, response)
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package gql
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ResponseCache the pluggable cache of query responses
+type ResponseCache interface {
+	// Get returns the cached data of the key
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the data of the key with the time-to-live
+	Set(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	// DeletePrefix removes cached data of keys that start with the prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// WithResponseCache sets the local cache of query responses. Queries are cached only if they enable CallLocalCache
+func WithResponseCache(cache ResponseCache) Option {
+	return func(opts *options) {
+		opts.responseCache = cache
+	}
+}
+
+// cachedDirective represents the @cached directive of Hasura query response caching
+type cachedDirective struct {
+	ttl     time.Duration
+	refresh bool
+}
+
+func (cd cachedDirective) Type() graphql.OptionType {
+	return graphql.OptionTypeOperationDirective
+}
+
+func (cd cachedDirective) String() string {
+	var args []string
+	if cd.ttl > 0 {
+		args = append(args, fmt.Sprintf("ttl: %d", int(cd.ttl.Seconds())))
+	}
+	if cd.refresh {
+		args = append(args, "refresh: true")
+	}
+	if len(args) == 0 {
+		return "@cached"
+	}
+	return fmt.Sprintf("@cached(%s)", strings.Join(args, ", "))
+}
+
+// CallCached adds the @cached directive to the query so Hasura caches the response.
+// The ttl is rounded down to seconds, zero uses the default ttl of Hasura. Refresh forces Hasura to refresh the cache.
+// The directive is rendered by Query and QueryRaw only, add it to raw queries of Exec methods manually
+func CallCached(ttl time.Duration, refresh bool) graphql.Option {
+	return cachedDirective{
+		ttl:     ttl,
+		refresh: refresh,
+	}
+}
+
+// CallLocalCache serves the query from the local response cache of the client, or stores the response with the ttl.
+// Cache keys start with the prefix, or the operation name if the prefix is empty, for invalidation with InvalidateCache
+func CallLocalCache(ttl time.Duration, prefix string) graphql.Option {
+	return callOption{
+		apply: func(opts *callOptions) {
+			opts.localCacheTTL = ttl
+			opts.localCachePrefix = prefix
+		},
+	}
+}
+
+// InvalidateCache removes locally cached responses of keys that start with the prefix
+func (c *HasuraClient) InvalidateCache(ctx context.Context, prefix string) error {
+	if c.responseCache == nil {
+		return nil
+	}
+	return c.responseCache.DeletePrefix(ctx, prefix)
+}
+
+// getCachedResponse returns the cache key of the query and the cached data if exists.
+// The key is empty if the operation isn't cacheable
+func (c *HasuraClient) getCachedResponse(ctx context.Context, op operation, query string, req *preparedRequest) (string, []byte, bool) {
+	if c.responseCache == nil || req.callOptions.localCacheTTL <= 0 || !op.isQueryOnly(query) {
+		return "", nil, false
+	}
+
+	prefix := req.callOptions.localCachePrefix
+	if prefix == "" {
+		prefix = getOperationNameFromOptions(req.options)
+	}
+	key, err := newCacheKey(prefix, query, op.variables, req.sessionVariables, c.cacheKeyIgnoredVariables()...)
+	if err != nil {
+		return "", nil, false
+	}
+
+	data, ok, err := c.responseCache.Get(ctx, key)
+	hit := err == nil && ok
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("cache_hit", hit))
+	return key, data, hit
+}
+
+func (c *HasuraClient) setCachedResponse(ctx context.Context, key string, data []byte, req *preparedRequest) {
+	_ = c.responseCache.Set(ctx, key, data, req.callOptions.localCacheTTL)
+}
+
+// cacheKeyIgnoredVariables returns session variables that don't affect query responses
+func (c *HasuraClient) cacheKeyIgnoredVariables() []string {
+	ignoredVariables := []string{XRequestId, HasuraClientName}
+	if c.rolePolicy != nil && c.rolePolicy.impersonatorHeader != "" {
+		ignoredVariables = append(ignoredVariables, c.rolePolicy.impersonatorHeader)
+	}
+	return ignoredVariables
+}
+
+// newCacheKey creates the cache key from the document, variables and session variables except ignored ones,
+// so cached responses never leak across roles, users and tenants
+func newCacheKey(prefix string, query string, variables map[string]any, sessionVariables SessionVariables, ignoredVariables ...string) (string, error) {
+	hash, err := hashOperation(query, variables, sessionVariables, ignoredVariables...)
+	if err != nil {
+		return "", err
+	}
+	return prefix + ":" + hash, nil
+}
+
+// hashOperation hashes the document, variables and session variables except ignored ones
+func hashOperation(query string, variables map[string]any, sessionVariables SessionVariables, ignoredVariables ...string) (string, error) {
+	vars, err := json.Marshal(variables)
+	if err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(sessionVariables))
+	for key := range sessionVariables {
+		if !slices.Contains(ignoredVariables, key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	hash := sha256.New()
+	hash.Write([]byte(query))
+	hash.Write([]byte{0})
+	hash.Write(vars)
+	for _, key := range keys {
+		hash.Write([]byte{0})
+		hash.Write([]byte(key))
+		hash.Write([]byte{'='})
+		hash.Write([]byte(sessionVariables[key]))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type cacheEntry struct {
+	key       string
+	data      []byte
+	expiresAt time.Time
+}
+
+// LRUResponseCache an in-process ResponseCache implementation that evicts the least recently used
+// or expired responses
+type LRUResponseCache struct {
+	capacity int
+	mu       sync.Mutex
+	items    map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+var _ ResponseCache = (*LRUResponseCache)(nil)
+
+// NewLRUResponseCache creates an in-process LRU cache with the maximum number of responses.
+// The cache is unbounded if the capacity is zero or negative
+func NewLRUResponseCache(capacity int) *LRUResponseCache {
+	return &LRUResponseCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get returns the cached data of the key
+func (lc *LRUResponseCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	elem, ok := lc.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := elem.Value.(cacheEntry)
+	if !lc.now().Before(entry.expiresAt) {
+		lc.removeElement(elem)
+		return nil, false, nil
+	}
+	lc.order.MoveToFront(elem)
+
+	return entry.data, true, nil
+}
+
+// Set stores the data of the key with the time-to-live
+func (lc *LRUResponseCache) Set(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	entry := cacheEntry{
+		key:       key,
+		data:      data,
+		expiresAt: lc.now().Add(ttl),
+	}
+	if elem, ok := lc.items[key]; ok {
+		elem.Value = entry
+		lc.order.MoveToFront(elem)
+		return nil
+	}
+
+	lc.items[key] = lc.order.PushFront(entry)
+	for lc.capacity > 0 && lc.order.Len() > lc.capacity {
+		lc.removeElement(lc.order.Back())
+	}
+
+	return nil
+}
+
+// DeletePrefix removes cached data of keys that start with the prefix
+func (lc *LRUResponseCache) DeletePrefix(ctx context.Context, prefix string) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	for key, elem := range lc.items {
+		if strings.HasPrefix(key, prefix) {
+			lc.removeElement(elem)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of cached responses
+func (lc *LRUResponseCache) Len() int {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	return lc.order.Len()
+}
+
+func (lc *LRUResponseCache) removeElement(elem *list.Element) {
+	lc.order.Remove(elem)
+	delete(lc.items, elem.Value.(cacheEntry).key)
+}
@@ -0,0 +1,118 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+	"gotest.tools/v3/assert"
+)
+
+func TestHasuraClient_LocalCache(t *testing.T) {
+	var requests atomic.Int32
+	var lastQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var body struct {
+			Query string `json:"query"`
+		}
+		assert.Check(t, json.NewDecoder(r.Body).Decode(&body))
+		lastQuery = body.Query
+		_, _ = w.Write([]byte(`{"data": {"users": [{"id": 1}]}}`))
+	}))
+	defer server.Close()
+
+	cache := NewLRUResponseCache(10)
+	client := NewAdminClient(server.URL, "secret", WithResponseCache(cache))
+	var result struct {
+		Users []struct {
+			ID int `graphql:"id"`
+		} `graphql:"users"`
+	}
+	options := []graphql.Option{graphql.OperationName("GetUsers"), CallCached(time.Minute, false), CallLocalCache(time.Minute, "")}
+
+	assert.NilError(t, client.Query(context.Background(), &result, nil, options...))
+	assert.Equal(t, "query GetUsers @cached(ttl: 60) {users{id}}", lastQuery)
+	assert.Equal(t, 1, result.Users[0].ID)
+
+	result.Users = nil
+	assert.NilError(t, client.Query(context.Background(), &result, nil, options...))
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Equal(t, 1, result.Users[0].ID)
+
+	// different roles and users never share cached responses
+	userClient, err := client.AsRole("user", "1")
+	assert.NilError(t, err)
+	_, err = userClient.QueryRaw(context.Background(), &result, nil, options...)
+	assert.NilError(t, err)
+	anotherUser, err := client.AsRole("user", "2")
+	assert.NilError(t, err)
+	_, err = anotherUser.QueryRaw(context.Background(), &result, nil, options...)
+	assert.NilError(t, err)
+	bs, err := userClient.QueryRaw(context.Background(), &result, nil, options...)
+	assert.NilError(t, err)
+	assert.Equal(t, `{"users": [{"id": 1}]}`, string(bs))
+	assert.Equal(t, int32(3), requests.Load())
+	assert.Equal(t, 3, cache.Len())
+
+	// mutations are never cached
+	_, err = client.ExecRaw(context.Background(), "mutation { delete_users(where: {}) { affected_rows } }", nil, CallLocalCache(time.Minute, "users"))
+	assert.NilError(t, err)
+	assert.Equal(t, 3, cache.Len())
+	for _, query := range []string{
+		"# c\nmutation { delete_users(where: {}) { affected_rows } }",
+		"fragment F on users { id }\nmutation { delete_users(where: {}) { returning { ...F } } }",
+		"query { users { id } }\nmutation { delete_users(where: {}) { affected_rows } }",
+		"query { users { id }",
+	} {
+		_, err = client.ExecRaw(context.Background(), query, nil, CallLocalCache(time.Minute, "users"))
+		assert.NilError(t, err)
+		assert.Equal(t, 3, cache.Len())
+	}
+	requestCount := requests.Load()
+	_, err = client.ExecRaw(context.Background(), "# c\nmutation { delete_users(where: {}) { affected_rows } }", nil, CallLocalCache(time.Minute, "users"))
+	assert.NilError(t, err)
+	assert.Equal(t, requestCount+1, requests.Load())
+	assert.Equal(t, operationMutation, getOperationType("# c\nmutation { delete_users(where: {}) { affected_rows } }"))
+
+	assert.NilError(t, client.InvalidateCache(context.Background(), "GetUsers"))
+	assert.Equal(t, 0, cache.Len())
+	_, err = client.QueryRaw(context.Background(), &result, nil, CallCached(0, true))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(lastQuery, "@cached(refresh: true) {users{id}}"))
+}
+
+func TestLRUResponseCache(t *testing.T) {
+	now := time.Now()
+	cache := NewLRUResponseCache(2)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	assert.NilError(t, cache.Set(ctx, "a:1", []byte("1"), time.Second))
+	assert.NilError(t, cache.Set(ctx, "b:1", []byte("2"), time.Minute))
+	_, ok, _ := cache.Get(ctx, "a:1")
+	assert.Assert(t, ok)
+	assert.NilError(t, cache.Set(ctx, "c:1", []byte("3"), time.Minute))
+	_, ok, _ = cache.Get(ctx, "b:1")
+	assert.Assert(t, !ok)
+
+	now = now.Add(2 * time.Second)
+	_, ok, _ = cache.Get(ctx, "a:1")
+	assert.Assert(t, !ok)
+	data, ok, _ := cache.Get(ctx, "c:1")
+	assert.Assert(t, ok)
+	assert.Equal(t, "3", string(data))
+}
+
+func TestHasuraClient_CacheKeyIgnoredVariables(t *testing.T) {
+	assert.DeepEqual(t, []string{XRequestId, HasuraClientName}, NewAdminClient("http://localhost:8080/v1/graphql", "secret").cacheKeyIgnoredVariables())
+
+	client := NewAdminClient("http://localhost:8080/v1/graphql", "secret", WithImpersonatorHeader("X-Impersonated-By"))
+	assert.DeepEqual(t, []string{XRequestId, HasuraClientName, "x-impersonated-by"}, client.cacheKeyIgnoredVariables())
+}
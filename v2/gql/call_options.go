@@ -0,0 +1,143 @@
+package gql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+// optionTypeCall the option type of per-call settings that are applied by the client and never rendered in the query
+const optionTypeCall graphql.OptionType = "hasura_call"
+
+// ErrAdminSecretOverride is returned when a per-call option sets the admin secret without CallAllowAdminSecret
+var ErrAdminSecretOverride = errors.New("per-call options cannot override the admin secret")
+
+type callOptions struct {
+	headers          SessionVariables
+	removedHeaders   []string
+	allowAdminSecret bool
+	responseMetadata *ResponseMetadata
+	requestDump      *RequestDump
+	localCacheTTL    time.Duration
+	localCachePrefix string
+	// rootField the root field that generic helpers unwrap
+	rootField *string
+}
+
+// callOption implements graphql.Option for per-call settings of the Hasura client
+type callOption struct {
+	apply func(opts *callOptions)
+}
+
+func (co callOption) Type() graphql.OptionType {
+	return optionTypeCall
+}
+
+func (co callOption) String() string {
+	return ""
+}
+
+// CallHeader adds or overrides a session variable or header for a single request.
+// The key is case-insensitive, the same as SessionVariables.Set.
+// Role, user id and backend-only changes are checked by the role policy, the same as With
+func CallHeader(key string, value string) graphql.Option {
+	return callOption{
+		apply: func(opts *callOptions) {
+			opts.headers.Set(key, value)
+		},
+	}
+}
+
+// CallSessionVariables adds or overrides session variables for a single request
+func CallSessionVariables(variables map[string]string) graphql.Option {
+	return callOption{
+		apply: func(opts *callOptions) {
+			for k, v := range variables {
+				opts.headers.Set(k, v)
+			}
+		},
+	}
+}
+
+// CallWithoutHeader removes session variables or headers of the client for a single request
+func CallWithoutHeader(keys ...string) graphql.Option {
+	return callOption{
+		apply: func(opts *callOptions) {
+			for _, key := range keys {
+				opts.removedHeaders = append(opts.removedHeaders, strings.ToLower(key))
+			}
+		},
+	}
+}
+
+// CallAllowAdminSecret allows per-call options of the request to set or remove the admin secret
+func CallAllowAdminSecret() graphql.Option {
+	return callOption{
+		apply: func(opts *callOptions) {
+			opts.allowAdminSecret = true
+		},
+	}
+}
+
+// applyCallOptions merges per-call options into a copy of the client session variables
+// and returns the remaining options for the underlying GraphQL client
+func (c *HasuraClient) applyCallOptions(options []graphql.Option) (callOptions, SessionVariables, []graphql.Option, error) {
+	opts := callOptions{
+		headers: SessionVariables{},
+	}
+	gqlOptions := make([]graphql.Option, 0, len(options))
+	for _, opt := range options {
+		if co, ok := opt.(callOption); ok {
+			co.apply(&opts)
+			continue
+		}
+		gqlOptions = append(gqlOptions, opt)
+	}
+
+	sessionVariables := c.sessionVariables
+	if len(opts.headers) == 0 && len(opts.removedHeaders) == 0 {
+		return opts, sessionVariables, gqlOptions, nil
+	}
+
+	if !opts.allowAdminSecret {
+		if _, ok := opts.headers[XHasuraAdminSecret]; ok {
+			return opts, nil, nil, ErrAdminSecretOverride
+		}
+		for _, key := range opts.removedHeaders {
+			if key == XHasuraAdminSecret {
+				return opts, nil, nil, ErrAdminSecretOverride
+			}
+		}
+	}
+
+	sessionVariables = sessionVariables.Clone()
+	for _, key := range opts.removedHeaders {
+		sessionVariables.Del(key)
+	}
+	for k, v := range opts.headers {
+		sessionVariables[k] = v
+	}
+	if err := c.authorizeCallSession(sessionVariables); err != nil {
+		return opts, nil, nil, err
+	}
+
+	return opts, sessionVariables, gqlOptions, nil
+}
+
+// authorizeCallSession checks role, user and backend-only changes of a single request
+// with the same guard and role policy of derived clients
+func (c *HasuraClient) authorizeCallSession(sessionVariables SessionVariables) error {
+	role := effectiveRole(sessionVariables)
+	userID := sessionVariables.Get(XHasuraUserID)
+	backendOnly := strings.EqualFold(sessionVariables.Get(XHasuraUseBackendOnlyPermissions), "true")
+	if role == effectiveRole(c.sessionVariables) && userID == c.sessionVariables.Get(XHasuraUserID) && backendOnly == c.IsBackendOnly() {
+		return nil
+	}
+	if role != "" && role != c.sessionVariables.GetRole() && !c.canAssumeRole() {
+		return fmt.Errorf("cannot promote to role <%s>", role)
+	}
+	return c.rolePolicy.authorize(c.newRoleTransition("Call", role, userID))
+}
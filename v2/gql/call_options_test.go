@@ -0,0 +1,77 @@
+package gql
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+	"gotest.tools/v3/assert"
+)
+
+func TestHasuraClient_CallOptions(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"data": {"__typename": "query_root"}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewAdminClient(server.URL, "secret", WithImpersonatorHeader(XHasuraImpersonator)).AsRole("user", "1")
+	assert.NilError(t, err)
+
+	_, err = client.ExecRaw(context.Background(), "query { __typename }", nil,
+		graphql.OperationName("GetTypename"),
+		CallHeader("X-Hasura-Tenant-Id", "10"),
+		CallSessionVariables(map[string]string{"X-Hasura-User-Id": "2", "X-Feature-Flag": "on"}),
+		CallWithoutHeader(XHasuraImpersonator),
+	)
+	assert.NilError(t, err)
+	assert.Equal(t, "10", header.Get("X-Hasura-Tenant-Id"))
+	assert.Equal(t, "2", header.Get("X-Hasura-User-Id"))
+	assert.Equal(t, "on", header.Get("X-Feature-Flag"))
+	assert.Equal(t, "user", header.Get("X-Hasura-Role"))
+	assert.Equal(t, "", header.Get(XHasuraImpersonator))
+	assert.Equal(t, "1", client.sessionVariables.Get(XHasuraUserID))
+
+	_, err = client.ExecRaw(context.Background(), "query { __typename }", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, "", header.Get("X-Hasura-Tenant-Id"))
+	assert.Equal(t, "1", header.Get("X-Hasura-User-Id"))
+
+	var result struct {
+		Typename string `graphql:"__typename"`
+	}
+	err = client.Query(context.Background(), &result, nil, CallHeader("X-Hasura-Admin-Secret", "other"))
+	assert.ErrorIs(t, err, ErrAdminSecretOverride)
+	err = client.Query(context.Background(), &result, nil, CallWithoutHeader("X-Hasura-Admin-Secret"))
+	assert.ErrorIs(t, err, ErrAdminSecretOverride)
+
+	// role changes of a single request are checked by the role policy
+	var transitions []RoleTransition
+	policyClient, err := NewAdminClient(server.URL, "secret",
+		WithAllowedRoles("user"),
+		WithRoleAuditHook(func(transition RoleTransition) {
+			transitions = append(transitions, transition)
+		}),
+	).AsRole("user", "1")
+	assert.NilError(t, err)
+	err = policyClient.Query(context.Background(), &result, nil, CallHeader(XHasuraRole, RoleAdmin))
+	assert.ErrorIs(t, err, ErrRoleEscalationDenied)
+	err = policyClient.Query(context.Background(), &result, nil, CallWithoutHeader(XHasuraRole))
+	assert.ErrorIs(t, err, ErrRoleEscalationDenied)
+	err = policyClient.Query(context.Background(), &result, nil, CallHeader(XHasuraUserID, "2"))
+	assert.NilError(t, err)
+	assert.Equal(t, 4, len(transitions))
+	assert.Equal(t, "Call", transitions[3].Method)
+	assert.Equal(t, "2", transitions[3].ToUserID)
+
+	_, err = NewHasuraClient(server.URL).ExecRaw(context.Background(), "query { __typename }", nil, CallHeader(XHasuraRole, "user"))
+	assert.ErrorContains(t, err, "cannot promote to role <user>")
+
+	err = client.Query(context.Background(), &result, nil, CallHeader("X-Hasura-Admin-Secret", "other"), CallAllowAdminSecret())
+	assert.NilError(t, err)
+	assert.Equal(t, "other", header.Get("X-Hasura-Admin-Secret"))
+	assert.Equal(t, "query_root", result.Typename)
+}
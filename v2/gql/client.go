@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/hasura/go-graphql-client"
@@ -23,17 +24,33 @@ const (
 var (
 	tracer                = otel.Tracer("github.com/hgiasac/hasura-utils/v2/gql")
 	errPromoteAdminDenied = errors.New("cannot promote to admin")
+	errBackendOnlyDenied  = errors.New("backend-only role requires the admin secret")
 )
 
 type options struct {
-	timeout     time.Duration
-	clientName  string
-	adminSecret string
-	debug       bool
+	timeout        time.Duration
+	clientName     string
+	adminSecret    string
+	debug          bool
+	rolePolicy     rolePolicy
+	backendOnly    *backendOnlyRole
+	tenant         tenantOptions
+	logging        logOptions
+	responseCache  ResponseCache
+	singleflight   bool
+	allowlist      allowlistOptions
+	readOnly       bool
+	responseErrors bool
+}
+
+type backendOnlyRole struct {
+	role   string
+	userID string
 }
 
 var defaultOptions = options{
-	timeout: 30 * time.Second,
+	timeout:    30 * time.Second,
+	rolePolicy: defaultRolePolicy,
 }
 
 type Option func(*options)
@@ -66,6 +83,18 @@ func WithDebug(value bool) Option {
 	}
 }
 
+// WithBackendOnlyRole sets the role and backend-only permissions to the default session of the hasura client.
+// The role is checked by the role policy, the same as AsBackendOnly. If the admin secret is empty or the role is denied,
+// Err returns the error and operations of the client fail with it
+func WithBackendOnlyRole(role string, userID string) Option {
+	return func(opts *options) {
+		opts.backendOnly = &backendOnlyRole{
+			role:   role,
+			userID: userID,
+		}
+	}
+}
+
 // HasuraClientConfig input config for Client
 type HasuraClientConfig struct {
 	BaseURL     string            `envconfig:"BASE_URL" env:"BASE_URL" default:""`
@@ -79,10 +108,22 @@ type HasuraClientConfig struct {
 // HasuraClient represents a graphql client with Hasura credential
 type HasuraClient struct {
 	client.Client
+	httpClient       *http.Client
 	endpoint         string
 	adminSecret      string
 	clientName       string
 	sessionVariables SessionVariables
+	rolePolicy       *rolePolicy
+	tenancy          *tenancy
+	logger           *operationLogger
+	debug            bool
+	responseCache    ResponseCache
+	inflight         *flightGroup
+	allowlist        allowlistOptions
+	readOnly         bool
+	responseErrors   bool
+	// err the error of invalid options that operations of the client fail with
+	err error
 }
 
 // NewHasuraClient creates a new GraphQL client for Hasura with the HTTP transport
@@ -104,13 +145,42 @@ func NewHasuraClient(endpoint string, options ...Option) *HasuraClient {
 		sessionVariables.Set(HasuraClientName, opts.clientName)
 	}
 
-	return &HasuraClient{
-		Client:           client.NewClient(endpoint, buildHttpClient(opts.timeout)).WithDebug(opts.debug),
+	httpClient := buildHttpClient(opts.timeout)
+	c := &HasuraClient{
+		Client:           client.NewClient(endpoint, httpClient).WithDebug(opts.debug),
+		httpClient:       httpClient,
 		adminSecret:      opts.adminSecret,
 		clientName:       opts.clientName,
 		sessionVariables: sessionVariables,
 		endpoint:         endpoint,
+		rolePolicy:       &opts.rolePolicy,
+		tenancy:          newTenancy(opts, httpClient),
+		logger:           newOperationLogger(opts.logging),
+		debug:            opts.debug,
+		responseCache:    opts.responseCache,
+		inflight:         newFlightGroup(opts.singleflight),
+		allowlist:        opts.allowlist,
+		readOnly:         opts.readOnly,
+		responseErrors:   opts.responseErrors,
+	}
+	if opts.backendOnly == nil {
+		return c
 	}
+	if opts.adminSecret == "" {
+		c.err = errBackendOnlyDenied
+		return c
+	}
+	backendClient, err := c.AsBackendOnly(opts.backendOnly.role, opts.backendOnly.userID)
+	if err != nil {
+		c.err = err
+		return c
+	}
+	return backendClient
+}
+
+// Err returns the error of invalid client options, e.g. WithBackendOnlyRole without the admin secret
+func (c *HasuraClient) Err() error {
+	return c.err
 }
 
 // NewAdminClient creates a new Hasura GraphQL client with admin role
@@ -131,15 +201,20 @@ func NewHasuraClientFromConfig(config HasuraClientConfig) *HasuraClient {
 	}
 
 	for k, v := range config.Headers {
-		sessionVariables[k] = v
+		sessionVariables.Set(k, v)
 	}
 
+	httpClient := buildHttpClient(config.Timeout)
+	policy := defaultRolePolicy
 	return &HasuraClient{
-		Client:           client.NewClient(endpoint, buildHttpClient(config.Timeout)).WithDebug(config.Debug),
+		Client:           client.NewClient(endpoint, httpClient).WithDebug(config.Debug),
+		httpClient:       httpClient,
 		adminSecret:      config.AdminSecret,
 		clientName:       sessionVariables.Get(HasuraClientName),
 		sessionVariables: sessionVariables,
 		endpoint:         endpoint,
+		rolePolicy:       &policy,
+		debug:            config.Debug,
 	}
 }
 
@@ -157,111 +232,329 @@ func (c HasuraClient) getDefaultSessionVariables() SessionVariables {
 }
 
 func (c *HasuraClient) Query(ctx context.Context, q any, variables map[string]any, options ...graphql.Option) error {
-	ctx, span := c.startSpan(ctx, "Query", options)
-	defer span.End()
-	ctx = setHeaders(ctx, c.sessionVariables.ToStringMap())
-	err := c.Client.Query(ctx, q, variables, options...)
-	if err != nil {
-		span.SetStatus(codes.Error, "query failure")
-		span.RecordError(err)
-	}
+	_, err := c.do(ctx, operation{
+		method:         "Query",
+		operationType:  operationQuery,
+		failureMessage: "query failure",
+		value:          q,
+		variables:      variables,
+		result:         q,
+	}, options)
 	return err
 }
 
 func (c *HasuraClient) QueryRaw(ctx context.Context, q any, variables map[string]any, options ...graphql.Option) ([]byte, error) {
-	ctx, span := c.startSpan(ctx, "QueryRaw", options)
-	defer span.End()
-	ctx = setHeaders(ctx, c.sessionVariables.ToStringMap())
-	bs, err := c.Client.QueryRaw(ctx, q, variables, options...)
-	if err != nil {
-		span.SetStatus(codes.Error, "query failure")
-		span.RecordError(err)
-	}
-	return bs, err
+	return c.do(ctx, operation{
+		method:         "QueryRaw",
+		operationType:  operationQuery,
+		failureMessage: "query failure",
+		value:          q,
+		variables:      variables,
+	}, options)
 }
 
 func (c *HasuraClient) Mutate(ctx context.Context, m any, variables map[string]any, options ...graphql.Option) error {
-	ctx, span := c.startSpan(ctx, "Mutate", options)
-	defer span.End()
-	ctx = setHeaders(ctx, c.sessionVariables.ToStringMap())
-	err := c.Client.Mutate(ctx, m, variables, options...)
-	if err != nil {
-		span.SetStatus(codes.Error, "mutation failure")
-		span.RecordError(err)
-	}
+	_, err := c.do(ctx, operation{
+		method:         "Mutate",
+		operationType:  operationMutation,
+		failureMessage: "mutation failure",
+		value:          m,
+		variables:      variables,
+		result:         m,
+	}, options)
 	return err
 }
 
 func (c *HasuraClient) MutateRaw(ctx context.Context, m any, variables map[string]any, options ...graphql.Option) ([]byte, error) {
-	ctx, span := c.startSpan(ctx, "MutateRaw", options)
+	return c.do(ctx, operation{
+		method:         "MutateRaw",
+		operationType:  operationMutation,
+		failureMessage: "mutation failure",
+		value:          m,
+		variables:      variables,
+	}, options)
+}
+
+func (c *HasuraClient) Exec(ctx context.Context, query string, m any, variables map[string]any, options ...graphql.Option) error {
+	_, err := c.do(ctx, operation{
+		method:         "Exec",
+		operationType:  getOperationType(query),
+		failureMessage: "exec failure",
+		query:          query,
+		variables:      variables,
+		result:         m,
+	}, options)
+	return err
+}
+
+func (c *HasuraClient) ExecRaw(ctx context.Context, query string, variables map[string]any, options ...graphql.Option) ([]byte, error) {
+	return c.do(ctx, operation{
+		method:         "ExecRaw",
+		operationType:  getOperationType(query),
+		failureMessage: "exec failure",
+		query:          query,
+		variables:      variables,
+	}, options)
+}
+
+// operation represents a GraphQL operation of the client
+type operation struct {
+	// method the name of the client method, e.g. QueryRaw
+	method         string
+	operationType  string
+	failureMessage string
+	// query the raw query of Exec methods
+	query string
+	// value the struct that the query of Query and Mutate methods is built from
+	value     any
+	variables map[string]any
+	// result the decoded response of non-raw methods
+	result any
+}
+
+// document returns the query string of the operation
+func (op operation) document(options []graphql.Option) (string, error) {
+	if op.value == nil {
+		return op.query, nil
+	}
+	if op.operationType == operationMutation {
+		return graphql.ConstructMutation(op.value, op.variables, options...)
+	}
+	return graphql.ConstructQuery(op.value, op.variables, options...)
+}
+
+// isQueryOnly checks if the document has query operations only. Raw documents are parsed,
+// so mutations after comments, fragments or other operations are detected
+func (op operation) isQueryOnly(query string) bool {
+	if op.query == "" {
+		return op.operationType == operationQuery
+	}
+	operationTypes, err := parseOperationTypes(query)
+	if err != nil {
+		return false
+	}
+	for _, operationType := range operationTypes {
+		if operationType != operationQuery {
+			return false
+		}
+	}
+	return true
+}
+
+// preparedRequest represents the resolved settings of an operation request
+type preparedRequest struct {
+	client           client.Client
+	endpoint         string
+	options          []graphql.Option
+	callOptions      callOptions
+	sessionVariables SessionVariables
+}
+
+// do runs the operation in a span with the session variables of the client and returns the raw data.
+// The data is decoded into the result of non-raw methods
+func (c *HasuraClient) do(ctx context.Context, op operation, options []graphql.Option) ([]byte, error) {
+	startedAt := time.Now()
+	ctx, span := c.startSpan(ctx, op.method, options)
 	defer span.End()
-	ctx = setHeaders(ctx, c.sessionVariables.ToStringMap())
-	bs, err := c.Client.MutateRaw(ctx, m, variables, options...)
+
+	operationName := getOperationNameFromOptions(options)
+	ctx, req, err := c.prepareRequest(ctx, options)
+	var bs []byte
+	if err == nil {
+		bs, err = c.execute(ctx, op, req)
+		err = c.finishRequest(ctx, span, err)
+	}
 	if err != nil {
-		span.SetStatus(codes.Error, "mutation failure")
+		span.SetStatus(codes.Error, op.failureMessage)
 		span.RecordError(err)
 	}
+	c.logger.log(ctx, c, op, operationName, time.Since(startedAt), bs, err)
+	if c.debug && err != nil {
+		c.logRequestDump(ctx, err)
+	}
+
 	return bs, err
 }
 
-func (c *HasuraClient) Exec(ctx context.Context, query string, m any, variables map[string]any, options ...graphql.Option) error {
-	ctx, span := c.startSpan(ctx, "Exec", options)
-	defer span.End()
-	ctx = setHeaders(ctx, c.sessionVariables.ToStringMap())
-	err := c.Client.Exec(ctx, query, m, variables, options...)
+// execute sends the operation request, or serves it from the local cache,
+// and decodes the data into the result of non-raw methods
+func (c *HasuraClient) execute(ctx context.Context, op operation, req *preparedRequest) ([]byte, error) {
+	query, err := op.document(req.options)
 	if err != nil {
-		span.SetStatus(codes.Error, "exec failure")
-		span.RecordError(err)
+		return nil, err
 	}
-	return err
+	if err := c.checkReadOnly(op, query); err != nil {
+		return nil, err
+	}
+	if err := c.checkAllowlist(ctx, op, query, getOperationNameFromOptions(req.options)); err != nil {
+		return nil, err
+	}
+
+	cacheKey, data, ok := c.getCachedResponse(ctx, op, query, req)
+	if !ok {
+		data, err = c.send(ctx, op, query, req)
+		if err == nil && cacheKey != "" {
+			c.setCachedResponse(ctx, cacheKey, data, req)
+		}
+	}
+	if op.result == nil || len(data) == 0 {
+		return data, err
+	}
+
+	if decodeErr := graphql.UnmarshalGraphQL(data, op.result); decodeErr != nil {
+		var gqlErrors graphql.Errors
+		errors.As(err, &gqlErrors)
+		err = append(gqlErrors, graphql.Error{
+			Message:    decodeErr.Error(),
+			Extensions: map[string]any{"code": graphql.ErrGraphQLDecode},
+		})
+	}
+
+	return data, err
 }
 
-func (c *HasuraClient) ExecRaw(ctx context.Context, query string, variables map[string]any, options ...graphql.Option) ([]byte, error) {
-	ctx, span := c.startSpan(ctx, "ExecRaw", options)
-	defer span.End()
-	ctx = setHeaders(ctx, c.sessionVariables.ToStringMap())
-	bs, err := c.Client.ExecRaw(ctx, query, variables, options...)
+// send sends the operation request. Identical in-flight queries are coalesced if singleflight is enabled
+func (c *HasuraClient) send(ctx context.Context, op operation, query string, req *preparedRequest) ([]byte, error) {
+	if c.inflight == nil || op.value == nil || op.operationType != operationQuery {
+		return req.client.ExecRaw(ctx, query, op.variables, req.options...)
+	}
+
+	key, err := hashOperation(query, op.variables, req.sessionVariables, XRequestId)
 	if err != nil {
-		span.SetStatus(codes.Error, "exec failure")
-		span.RecordError(err)
+		return nil, err
 	}
-	return bs, err
+	return c.inflight.do(ctx, key, func(ctx context.Context) ([]byte, error) {
+		return req.client.ExecRaw(ctx, query, op.variables, req.options...)
+	})
 }
 
-// AsRole allows the client to act on behalf of a new role
+// prepareRequest sets the session variables with per-call overrides, the tenant and the request id
+// to the request context. It resolves the GraphQL client of the tenant
+// and strips per-call options that the underlying GraphQL client doesn't support
+func (c *HasuraClient) prepareRequest(ctx context.Context, options []graphql.Option) (context.Context, *preparedRequest, error) {
+	if c.err != nil {
+		return ctx, nil, c.err
+	}
+	callOpts, sessionVariables, gqlOptions, err := c.applyCallOptions(options)
+	if err != nil {
+		return ctx, nil, err
+	}
+	gqlClient, endpoint, sessionVariables, err := c.resolveTenant(ctx, sessionVariables)
+	if err != nil {
+		return ctx, nil, err
+	}
+	requestID, sessionVariables := c.resolveRequestID(ctx, sessionVariables)
+	ctx = context.WithValue(ctx, requestStateContextKey{}, &requestState{
+		requestID: requestID,
+		role:      sessionVariables.GetRole(),
+		startedAt: time.Now(),
+		metadata:  callOpts.responseMetadata,
+		dump:      callOpts.requestDump,
+		// the dump clones headers and reads the body again, so it is built only if something reads it
+		dumpRequest: c.debug || c.responseErrors || callOpts.requestDump != nil,
+	})
+
+	return setHeaders(ctx, sessionVariables.ToStringMap()), &preparedRequest{
+		client:           gqlClient,
+		endpoint:         endpoint,
+		options:          gqlOptions,
+		callOptions:      callOpts,
+		sessionVariables: sessionVariables,
+	}, nil
+}
+
+// SessionOption modifies the session variables of a derived client
+type SessionOption func(sessionVariables SessionVariables)
+
+// WithRole sets the role of the derived client
+func WithRole(role string) SessionOption {
+	return func(sessionVariables SessionVariables) {
+		sessionVariables.Set(XHasuraRole, role)
+	}
+}
+
+// WithUserID sets the user id of the derived client. An empty value removes the user id
+func WithUserID(userID string) SessionOption {
+	return func(sessionVariables SessionVariables) {
+		if userID == "" {
+			sessionVariables.Del(XHasuraUserID)
+			return
+		}
+		sessionVariables.Set(XHasuraUserID, userID)
+	}
+}
+
+// WithHeader sets a session variable or header of the derived client
+func WithHeader(key string, value string) SessionOption {
+	return func(sessionVariables SessionVariables) {
+		sessionVariables.Set(key, value)
+	}
+}
+
+// WithoutHeader removes session variables or headers from the derived client
+func WithoutHeader(keys ...string) SessionOption {
+	return func(sessionVariables SessionVariables) {
+		for _, key := range keys {
+			sessionVariables.Del(key)
+		}
+	}
+}
+
+// With derives a new client from the current session with the session options.
+// The derived client carries every option of the source client, only the session variables are changed.
+// Changing the role requires the admin secret or JWT auth and is checked by the role policy
+func (c *HasuraClient) With(options ...SessionOption) (*HasuraClient, error) {
+	sessionVariables := c.sessionVariables.Clone()
+	for _, apply := range options {
+		apply(sessionVariables)
+	}
+
+	role := sessionVariables.GetRole()
+	if role != "" && role != c.sessionVariables.GetRole() && !c.canAssumeRole() {
+		return nil, fmt.Errorf("cannot promote to role <%s>", role)
+	}
+
+	return c.derive("With", sessionVariables)
+}
+
+// As allows the client to act on behalf of new session variables.
+// The session is reset to the default session of the client before the variables are applied
 func (c *HasuraClient) As(variables map[string]string) (*HasuraClient, error) {
 	sessionVariables := c.getDefaultSessionVariables()
-
 	for k, v := range variables {
-		sessionVariables[k] = v
+		sessionVariables.Set(k, v)
 	}
 
-	return &HasuraClient{
-		Client:           c.Client,
-		adminSecret:      c.adminSecret,
-		clientName:       c.clientName,
-		sessionVariables: sessionVariables,
-	}, nil
+	return c.derive("As", sessionVariables)
 }
 
-// AsRole allows the client to act on behalf of a new role
+// AsRole allows the client to act on behalf of a new role.
+// The client must have the admin secret or a JWT Authorization header
 func (c *HasuraClient) AsRole(role string, userId string) (*HasuraClient, error) {
-	if c.adminSecret == "" {
+	return c.asRole("AsRole", role, userId, false)
+}
+
+// AsBackendOnly allows the client to act on behalf of a new role with backend-only permissions,
+// e.g. backend-only inserts. The same guard of AsRole is applied
+func (c *HasuraClient) AsBackendOnly(role string, userId string) (*HasuraClient, error) {
+	return c.asRole("AsBackendOnly", role, userId, true)
+}
+
+func (c *HasuraClient) asRole(method string, role string, userId string, backendOnly bool) (*HasuraClient, error) {
+	if !c.canAssumeRole() {
 		return nil, fmt.Errorf("cannot promote to role <%s>", role)
 	}
 
-	sessionVariables := c.sessionVariables.FilterKey(XHasuraRole, XHasuraUserID)
+	sessionVariables := c.sessionVariables.FilterKey(XHasuraRole, XHasuraUserID, XHasuraUseBackendOnlyPermissions)
 	sessionVariables[XHasuraRole] = role
 	if userId != "" {
 		sessionVariables[XHasuraUserID] = userId
 	}
+	if backendOnly {
+		sessionVariables[XHasuraUseBackendOnlyPermissions] = "true"
+	}
 
-	return &HasuraClient{
-		Client:           c.Client,
-		adminSecret:      c.adminSecret,
-		clientName:       c.clientName,
-		sessionVariables: sessionVariables,
-	}, nil
+	return c.derive(method, sessionVariables)
 }
 
 // AsAdmin allows the client to act on behalf of an admin
@@ -269,14 +562,13 @@ func (c *HasuraClient) AsAdmin() (*HasuraClient, error) {
 	if c.adminSecret == "" {
 		return nil, errPromoteAdminDenied
 	}
-	sessionVariables := c.sessionVariables.FilterKey(XHasuraRole, XHasuraUserID)
 
-	return &HasuraClient{
-		Client:           c.Client,
-		adminSecret:      c.adminSecret,
-		clientName:       c.clientName,
-		sessionVariables: sessionVariables,
-	}, nil
+	sessionVariables := c.sessionVariables.FilterKey(XHasuraRole, XHasuraUserID, XHasuraUseBackendOnlyPermissions)
+	if c.rolePolicy != nil && c.rolePolicy.impersonatorHeader != "" {
+		sessionVariables.Del(c.rolePolicy.impersonatorHeader)
+	}
+
+	return c.derive("AsAdmin", sessionVariables)
 }
 
 // ForceAdmin allows the client to act on behalf of an admin, this function panics if the client cannot
@@ -296,16 +588,78 @@ func (c *HasuraClient) AsAnonymous() (*HasuraClient, error) {
 		newSession[HasuraClientName] = c.clientName
 	}
 
-	return &HasuraClient{
-		Client:           c.Client,
-		adminSecret:      c.adminSecret,
-		clientName:       c.clientName,
-		sessionVariables: newSession,
-	}, nil
+	return c.derive("AsAnonymous", newSession)
+}
+
+// derive clones the client with new session variables. The role transition is checked by the role policy
+// and the impersonator header is set if the role or user changes
+func (c *HasuraClient) derive(method string, sessionVariables SessionVariables) (*HasuraClient, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	role := effectiveRole(sessionVariables)
+	userID := sessionVariables.Get(XHasuraUserID)
+	if method == "AsAdmin" {
+		role = RoleAdmin
+	}
+	if method != "With" || role != effectiveRole(c.sessionVariables) || userID != c.sessionVariables.Get(XHasuraUserID) {
+		if err := c.rolePolicy.authorize(c.newRoleTransition(method, role, userID)); err != nil {
+			return nil, err
+		}
+		if role != RoleAdmin && role != "" {
+			c.setImpersonator(sessionVariables)
+		}
+	}
+
+	derived := *c
+	derived.sessionVariables = sessionVariables
+	return &derived, nil
+}
+
+// canAssumeRole checks if the client is trusted by Hasura to set the role, with the admin secret or JWT auth
+func (c *HasuraClient) canAssumeRole() bool {
+	return c.adminSecret != "" || c.sessionVariables.Get(authorizationHeader) != ""
+}
+
+// IsBackendOnly checks if the client uses backend-only permissions
+func (c *HasuraClient) IsBackendOnly() bool {
+	return strings.EqualFold(c.sessionVariables.Get(XHasuraUseBackendOnlyPermissions), "true")
+}
+
+func (c *HasuraClient) newRoleTransition(method string, role string, userID string) RoleTransition {
+	return RoleTransition{
+		Method:     method,
+		ClientName: c.clientName,
+		FromRole:   effectiveRole(c.sessionVariables),
+		FromUserID: c.sessionVariables.Get(XHasuraUserID),
+		ToRole:     role,
+		ToUserID:   userID,
+	}
+}
+
+// effectiveRole returns the role that Hasura resolves for the session variables.
+// Sessions with the admin secret and without a role act as admin
+func effectiveRole(sessionVariables SessionVariables) string {
+	role := sessionVariables.GetRole()
+	if role == "" && sessionVariables.Get(XHasuraAdminSecret) != "" {
+		return RoleAdmin
+	}
+	return role
+}
+
+// setImpersonator attributes the impersonated session to the source client
+func (c *HasuraClient) setImpersonator(sessionVariables SessionVariables) {
+	if c.rolePolicy == nil || c.rolePolicy.impersonatorHeader == "" || sessionVariables.Get(c.rolePolicy.impersonatorHeader) != "" {
+		return
+	}
+	sessionVariables.Set(c.rolePolicy.impersonatorHeader, c.impersonator())
 }
 
 func (c *HasuraClient) startSpan(ctx context.Context, name string, options []graphql.Option) (context.Context, trace.Span) {
 	ctx, span := tracer.Start(ctx, name, trace.WithAttributes(attribute.String("url", c.endpoint)))
+	if c.IsBackendOnly() {
+		span.SetAttributes(attribute.Bool("backend_only", true))
+	}
 	operationName := getOperationNameFromOptions(options)
 	if operationName != "" {
 		span.SetAttributes(attribute.String("operation_name", operationName))
@@ -321,7 +675,15 @@ type headerRoundTripper struct {
 
 func (h headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	h.setHeaders(req)
-	return h.rt.RoundTrip(req)
+	state := getRequestState(req.Context())
+	if state != nil {
+		state.recordRequest(req)
+	}
+	resp, err := h.rt.RoundTrip(req)
+	if resp != nil && state != nil {
+		state.recordResponse(resp)
+	}
+	return resp, err
 }
 
 func buildHttpClient(timeout time.Duration) *http.Client {
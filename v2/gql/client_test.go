@@ -0,0 +1,150 @@
+package gql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestHasuraClient_AsBackendOnly(t *testing.T) {
+	_, err := NewHasuraClient("http://localhost:8080/v1/graphql").AsBackendOnly("user", "1")
+	assert.ErrorContains(t, err, "cannot promote to role <user>")
+
+	client := NewAdminClient("http://localhost:8080/v1/graphql", "secret")
+	backendClient, err := client.AsBackendOnly("user", "1")
+	assert.NilError(t, err)
+	assert.Assert(t, backendClient.IsBackendOnly())
+	assert.Equal(t, "user", backendClient.sessionVariables.GetRole())
+	assert.Equal(t, "1", backendClient.sessionVariables.Get(XHasuraUserID))
+
+	userClient, err := backendClient.AsRole("user", "2")
+	assert.NilError(t, err)
+	assert.Assert(t, !userClient.IsBackendOnly())
+
+	jwtClient, err := NewHasuraClient("http://localhost:8080/v1/graphql").With(WithHeader("Authorization", "Bearer token"))
+	assert.NilError(t, err)
+	backendClient, err = jwtClient.AsBackendOnly("user", "")
+	assert.NilError(t, err)
+	assert.Assert(t, backendClient.IsBackendOnly())
+
+	optionClient := NewAdminClient("http://localhost:8080/v1/graphql", "secret", WithBackendOnlyRole("editor", ""))
+	assert.NilError(t, optionClient.Err())
+	assert.Assert(t, optionClient.IsBackendOnly())
+	assert.Equal(t, "editor", optionClient.sessionVariables.GetRole())
+
+	// the option fails without the admin secret
+	noSecretClient := NewHasuraClient("http://localhost:8080/v1/graphql", WithBackendOnlyRole("editor", ""))
+	assert.ErrorIs(t, noSecretClient.Err(), errBackendOnlyDenied)
+	assert.Assert(t, !noSecretClient.IsBackendOnly())
+	_, err = noSecretClient.ExecRaw(context.Background(), "query { __typename }", nil)
+	assert.ErrorIs(t, err, errBackendOnlyDenied)
+	_, err = noSecretClient.AsAnonymous()
+	assert.ErrorIs(t, err, errBackendOnlyDenied)
+
+	// the option is checked by the role policy
+	var transitions []RoleTransition
+	deniedClient := NewAdminClient("http://localhost:8080/v1/graphql", "secret",
+		WithAllowedRoles("user"),
+		WithRoleAuditHook(func(transition RoleTransition) {
+			transitions = append(transitions, transition)
+		}),
+		WithBackendOnlyRole("editor", ""),
+	)
+	assert.ErrorIs(t, deniedClient.Err(), ErrRoleEscalationDenied)
+	assert.Equal(t, 1, len(transitions))
+	assert.Equal(t, "AsBackendOnly", transitions[0].Method)
+}
+
+func TestNewHasuraClientFromConfig_Headers(t *testing.T) {
+	client := NewHasuraClientFromConfig(HasuraClientConfig{
+		URL:     "http://localhost:8080/v1/graphql",
+		Headers: map[string]string{"Authorization": "Bearer token", "X-Hasura-Role": "user"},
+	})
+	assert.Equal(t, "Bearer token", client.sessionVariables.Get(authorizationHeader))
+	assert.Equal(t, "user", client.sessionVariables.GetRole())
+
+	_, err := client.AsRole("editor", "1")
+	assert.NilError(t, err)
+}
+
+func TestHasuraClient_DerivationFieldParity(t *testing.T) {
+	var transitions []RoleTransition
+	client := NewHasuraClient(
+		"http://localhost:8080/v1/graphql",
+		WithAdminSecret("secret"),
+		WithClientName("test-client"),
+		WithAllowedRoles(RoleAdmin, "user", "editor"),
+		WithRoleAuditHook(func(transition RoleTransition) {
+			transitions = append(transitions, transition)
+		}),
+	)
+
+	derivations := map[string]func(c *HasuraClient) (*HasuraClient, error){
+		"As": func(c *HasuraClient) (*HasuraClient, error) {
+			return c.As(map[string]string{XHasuraRole: "user"})
+		},
+		"AsRole": func(c *HasuraClient) (*HasuraClient, error) {
+			return c.AsRole("user", "1")
+		},
+		"AsBackendOnly": func(c *HasuraClient) (*HasuraClient, error) {
+			return c.AsBackendOnly("user", "1")
+		},
+		"AsAdmin": func(c *HasuraClient) (*HasuraClient, error) {
+			return c.AsAdmin()
+		},
+		"AsAnonymous": func(c *HasuraClient) (*HasuraClient, error) {
+			return c.AsAnonymous()
+		},
+		"With": func(c *HasuraClient) (*HasuraClient, error) {
+			return c.With(WithRole("editor"), WithUserID("2"), WithHeader("X-Request-Id", "abc"))
+		},
+	}
+
+	expected := *client
+	expected.sessionVariables = nil
+	for name, derive := range derivations {
+		derived, err := derive(client)
+		assert.NilError(t, err, name)
+		// metadata and subscription requests of derived clients are sent to the endpoint of the source client
+		assert.Equal(t, "http://localhost:8080/v1/metadata", derived.MetadataURL(), name)
+
+		actual := *derived
+		actual.sessionVariables = nil
+		assert.Assert(t, reflect.DeepEqual(actual, expected), "%s: derived client fields differ from the source client", name)
+
+		// derivations of derived clients keep the same fields
+		derived, err = derive(derived)
+		assert.NilError(t, err, name)
+		actual = *derived
+		actual.sessionVariables = nil
+		assert.Assert(t, reflect.DeepEqual(actual, expected), "%s: nested derived client fields differ from the source client", name)
+	}
+	assert.Assert(t, len(transitions) > 0)
+}
+
+func TestHasuraClient_With(t *testing.T) {
+	client := NewAdminClient("http://localhost:8080/v1/graphql", "secret")
+	userClient, err := client.With(WithRole("user"), WithUserID("1"), WithHeader("X-Request-Id", "abc"))
+	assert.NilError(t, err)
+	assert.Equal(t, "user", userClient.sessionVariables.GetRole())
+	assert.Equal(t, "1", userClient.sessionVariables.Get(XHasuraUserID))
+	assert.Equal(t, "abc", userClient.sessionVariables.Get(XRequestId))
+	assert.Equal(t, "", userClient.sessionVariables.Get(XHasuraImpersonator))
+	assert.Equal(t, "", client.sessionVariables.GetRole())
+
+	requestClient, err := userClient.With(WithoutHeader("x-request-id"), WithUserID(""))
+	assert.NilError(t, err)
+	assert.Equal(t, "user", requestClient.sessionVariables.GetRole())
+	assert.Equal(t, "", requestClient.sessionVariables.Get(XRequestId))
+	assert.Equal(t, "", requestClient.sessionVariables.Get(XHasuraUserID))
+	assert.Equal(t, "abc", userClient.sessionVariables.Get(XRequestId))
+
+	_, err = NewHasuraClient("http://localhost:8080/v1/graphql").With(WithRole("user"))
+	assert.ErrorContains(t, err, "cannot promote to role <user>")
+
+	anonymous, err := NewHasuraClient("http://localhost:8080/v1/graphql").With(WithHeader("X-Request-Id", "abc"))
+	assert.NilError(t, err)
+	assert.Equal(t, "abc", anonymous.sessionVariables.Get(XRequestId))
+}
@@ -0,0 +1,371 @@
+package gql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenPunctuator tokenKind = iota
+	tokenName
+	tokenNumber
+	tokenString
+)
+
+// token represents a lexical token of GraphQL documents
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+// isWord checks if the token must be separated from adjacent words by a space
+func (t token) isWord() bool {
+	return t.kind == tokenName || t.kind == tokenNumber
+}
+
+// tokenize splits the GraphQL document into tokens, ignoring whitespace, commas and comments
+func tokenize(document string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(document); {
+		ch := document[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',':
+			i++
+		case strings.HasPrefix(document[i:], "\uFEFF"):
+			i += len("\uFEFF")
+		case ch == '#':
+			for i < len(document) && document[i] != '\n' && document[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(document[i:], "..."):
+			tokens = append(tokens, token{kind: tokenPunctuator, value: "..."})
+			i += 3
+		case strings.IndexByte("!$&():=@[]{|}", ch) >= 0:
+			tokens = append(tokens, token{kind: tokenPunctuator, value: string(ch)})
+			i++
+		case isNameStart(ch):
+			start := i
+			for i < len(document) && (isNameStart(document[i]) || isDigit(document[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenName, value: document[start:i]})
+		case ch == '-' || isDigit(ch):
+			start := i
+			i = scanNumber(document, i)
+			if i == start || document[i-1] == '-' {
+				return nil, fmt.Errorf("invalid number at position %d", start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: document[start:i]})
+		case strings.HasPrefix(document[i:], `"""`):
+			end := strings.Index(strings.ReplaceAll(document[i+3:], `\"""`, "xxxx"), `"""`)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated block string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: document[i : i+3+end+3]})
+			i += 3 + end + 3
+		case ch == '"':
+			start := i
+			i++
+			for i < len(document) && document[i] != '"' {
+				if document[i] == '\n' || document[i] == '\r' {
+					return nil, fmt.Errorf("unterminated string at position %d", start)
+				}
+				if document[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(document) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, value: document[start:i]})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", ch, i)
+		}
+	}
+
+	return tokens, nil
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func scanNumber(document string, i int) int {
+	if i < len(document) && document[i] == '-' {
+		i++
+	}
+	for i < len(document) && isDigit(document[i]) {
+		i++
+	}
+	if i < len(document) && document[i] == '.' {
+		i++
+		for i < len(document) && isDigit(document[i]) {
+			i++
+		}
+	}
+	if i < len(document) && (document[i] == 'e' || document[i] == 'E') {
+		i++
+		if i < len(document) && (document[i] == '+' || document[i] == '-') {
+			i++
+		}
+		for i < len(document) && isDigit(document[i]) {
+			i++
+		}
+	}
+	return i
+}
+
+// NormalizeQuery normalizes the GraphQL document by removing comments, commas and insignificant whitespace,
+// so documents that differ in formatting only have the same normalized form
+func NormalizeQuery(query string) (string, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return "", err
+	}
+
+	return renderTokens(tokens), nil
+}
+
+// QueryHash computes the stable SHA-256 hash of the normalized GraphQL document
+func QueryHash(query string) (string, error) {
+	normalized, err := NormalizeQuery(query)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CanonicalizeQuery normalizes the GraphQL document and sorts selections of every selection set,
+// so documents that differ in formatting and field order only have the same canonical form
+func CanonicalizeQuery(query string) (string, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return "", err
+	}
+
+	var results []token
+	depth := 0
+	for i := 0; i < len(tokens); {
+		t := tokens[i]
+		switch {
+		case t.value == "(" || t.value == "[":
+			depth++
+		case t.value == ")" || t.value == "]":
+			depth--
+		case t.value == "{" && depth == 0 && t.kind == tokenPunctuator:
+			selectionSet, next, err := canonicalSelectionSet(tokens, i)
+			if err != nil {
+				return "", err
+			}
+			results = append(results, selectionSet...)
+			i = next
+			continue
+		}
+		results = append(results, t)
+		i++
+	}
+
+	return renderTokens(results), nil
+}
+
+// CanonicalQueryHash computes the SHA-256 hash of the canonical GraphQL document
+func CanonicalQueryHash(query string) (string, error) {
+	canonical, err := CanonicalizeQuery(query)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalSelectionSet parses the selection set that starts at the index and returns the tokens of sorted selections
+func canonicalSelectionSet(tokens []token, start int) ([]token, int, error) {
+	var selections [][]token
+	i := start + 1
+	for {
+		if i >= len(tokens) {
+			return nil, 0, fmt.Errorf("unterminated selection set")
+		}
+		if tokens[i].value == "}" && tokens[i].kind == tokenPunctuator {
+			break
+		}
+		selection, next, err := canonicalSelection(tokens, i)
+		if err != nil {
+			return nil, 0, err
+		}
+		selections = append(selections, selection)
+		i = next
+	}
+	slices.SortStableFunc(selections, func(a, b []token) int {
+		return strings.Compare(renderTokens(a), renderTokens(b))
+	})
+
+	results := []token{tokens[start]}
+	for _, selection := range selections {
+		results = append(results, selection...)
+	}
+	return append(results, tokens[i]), i + 1, nil
+}
+
+// canonicalSelection parses a field, a fragment spread or an inline fragment
+func canonicalSelection(tokens []token, i int) ([]token, int, error) {
+	var results []token
+	consume := func() {
+		results = append(results, tokens[i])
+		i++
+	}
+	peek := func(value string) bool {
+		return i < len(tokens) && tokens[i].value == value && tokens[i].kind != tokenString
+	}
+
+	switch {
+	case peek("..."):
+		consume()
+		if peek("on") {
+			consume()
+		}
+		if i < len(tokens) && tokens[i].kind == tokenName {
+			consume()
+		}
+	case i < len(tokens) && tokens[i].kind == tokenName:
+		consume()
+		if peek(":") {
+			consume()
+			if i >= len(tokens) || tokens[i].kind != tokenName {
+				return nil, 0, fmt.Errorf("expected field name after alias")
+			}
+			consume()
+		}
+		if peek("(") {
+			next, err := skipBalanced(tokens, i)
+			if err != nil {
+				return nil, 0, err
+			}
+			results = append(results, tokens[i:next]...)
+			i = next
+		}
+	default:
+		return nil, 0, fmt.Errorf("unexpected token %s in selection set", tokens[i].value)
+	}
+
+	for peek("@") {
+		consume()
+		if i >= len(tokens) || tokens[i].kind != tokenName {
+			return nil, 0, fmt.Errorf("expected directive name")
+		}
+		consume()
+		if peek("(") {
+			next, err := skipBalanced(tokens, i)
+			if err != nil {
+				return nil, 0, err
+			}
+			results = append(results, tokens[i:next]...)
+			i = next
+		}
+	}
+
+	if peek("{") {
+		selectionSet, next, err := canonicalSelectionSet(tokens, i)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, selectionSet...)
+		i = next
+	}
+
+	return results, i, nil
+}
+
+// skipBalanced returns the index after the group of parentheses, brackets and braces that starts at the index
+func skipBalanced(tokens []token, start int) (int, error) {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		if tokens[i].kind != tokenPunctuator {
+			continue
+		}
+		switch tokens[i].value {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unbalanced %s", tokens[start].value)
+}
+
+// renderTokens joins tokens with a space between adjacent words only
+func renderTokens(tokens []token) string {
+	var sb strings.Builder
+	for i, t := range tokens {
+		if i > 0 && t.isWord() && tokens[i-1].isWord() {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(t.value)
+	}
+	return sb.String()
+}
+
+// parseOperationTypes parses operation types of definitions in the GraphQL document.
+// Query shorthands are query operations, fragment definitions are skipped
+func parseOperationTypes(query string) ([]string, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []string
+	for i := 0; i < len(tokens); {
+		t := tokens[i]
+		switch {
+		case t.kind == tokenPunctuator && t.value == "{":
+			results = append(results, operationQuery)
+		case t.kind == tokenName && (t.value == operationQuery || t.value == operationMutation || t.value == operationSubscription):
+			results = append(results, t.value)
+		case t.kind == tokenName && t.value == "fragment":
+		default:
+			return nil, fmt.Errorf("unexpected token %s at the start of a definition", t.value)
+		}
+
+		// skip to the end of the selection set of the definition.
+		// Braces of variable defaults and directive arguments are nested in parentheses
+		for i < len(tokens) && (tokens[i].kind != tokenPunctuator || tokens[i].value != "{") {
+			if tokens[i].kind == tokenPunctuator && tokens[i].value == "(" {
+				next, err := skipBalanced(tokens, i)
+				if err != nil {
+					return nil, err
+				}
+				i = next
+				continue
+			}
+			i++
+		}
+		if i >= len(tokens) {
+			return nil, fmt.Errorf("definition %s has no selection set", t.value)
+		}
+		next, err := skipBalanced(tokens, i)
+		if err != nil {
+			return nil, err
+		}
+		i = next
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("document has no operation")
+	}
+
+	return results, nil
+}
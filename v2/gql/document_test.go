@@ -0,0 +1,76 @@
+package gql
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	normalized, err := NormalizeQuery(`
+		# get users
+		query GetUsers($limit: Int = 10, $where: users_bool_exp!) @cached(ttl: 60) {
+			users(limit: $limit, where: $where, order_by: {id: asc}) {
+				id
+				name @include(if: true)
+				...UserFields
+				bio(format: """ block "string" """)
+				score(min: -1.5e3, label: "a, b # c")
+			}
+		}`)
+	assert.NilError(t, err)
+	assert.Equal(t, `query GetUsers($limit:Int=10$where:users_bool_exp!)@cached(ttl:60){users(limit:$limit where:$where order_by:{id:asc}){id name@include(if:true)...UserFields bio(format:""" block "string" """)score(min:-1.5e3 label:"a, b # c")}}`, normalized)
+
+	hash1, err := QueryHash("query { users { id name } }")
+	assert.NilError(t, err)
+	hash2, err := QueryHash("query {\n  users {\n    id,\n    name\n  }\n}")
+	assert.NilError(t, err)
+	assert.Equal(t, hash1, hash2)
+	hash3, err := QueryHash("query { users { name id } }")
+	assert.NilError(t, err)
+	assert.Assert(t, hash1 != hash3)
+
+	_, err = NormalizeQuery(`query { users(name: "foo) { id } }`)
+	assert.ErrorContains(t, err, "unterminated string")
+	_, err = NormalizeQuery(`query { users % }`)
+	assert.ErrorContains(t, err, "unexpected character")
+}
+
+func TestCanonicalizeQuery(t *testing.T) {
+	canonical, err := CanonicalizeQuery(`
+		query GetUsers($where: users_bool_exp = {name: {_eq: "b"}}) {
+			users(where: $where, order_by: {name: asc, id: desc}) {
+				name
+				... on users { email id }
+				id
+				posts: articles { title id }
+			}
+		}`)
+	assert.NilError(t, err)
+	assert.Equal(t, `query GetUsers($where:users_bool_exp={name:{_eq:"b"}}){users(where:$where order_by:{name:asc id:desc}){...on users{email id}id name posts:articles{id title}}}`, canonical)
+
+	hash1, err := CanonicalQueryHash("query { users { id name } }")
+	assert.NilError(t, err)
+	hash2, err := CanonicalQueryHash("query {\n  users {\n    name\n    id\n  }\n}")
+	assert.NilError(t, err)
+	assert.Equal(t, hash1, hash2)
+
+	_, err = CanonicalizeQuery(`query { users { id }`)
+	assert.ErrorContains(t, err, "unterminated selection set")
+}
+
+func TestParseOperationTypes(t *testing.T) {
+	operationTypes, err := parseOperationTypes(`
+		# mutation in comments is ignored
+		query GetUsers($where: users_bool_exp = {name: {_eq: "mutation"}}) { users(where: $where) { ...UserFields } }
+		fragment UserFields on users { id }
+		{ posts { id } }
+		mutation DeleteUsers @cached(ttl: 1) { delete_users(where: {}) { affected_rows } }`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{operationQuery, operationQuery, operationMutation}, operationTypes)
+
+	_, err = parseOperationTypes(`mutation DeleteUsers`)
+	assert.ErrorContains(t, err, "has no selection set")
+	_, err = parseOperationTypes(`fragment UserFields on users { id }`)
+	assert.ErrorContains(t, err, "document has no operation")
+}
@@ -0,0 +1,210 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+// MaskedValue the replacement of secret headers in request dumps
+const MaskedValue = "***"
+
+// DefaultAdminSecretEnv the default environment variable of the admin secret in request dumps
+const DefaultAdminSecretEnv = "HASURA_GRAPHQL_ADMIN_SECRET"
+
+// secret headers that are masked in request dumps
+var maskedDumpHeaders = []string{XHasuraAdminSecret, authorizationHeader, "Cookie"}
+
+// secret headers that are masked in the response metadata
+var maskedResponseHeaders = []string{"Set-Cookie"}
+
+// RequestDump represents a replayable HTTP request of an operation. Secret headers are masked
+type RequestDump struct {
+	Method   string
+	Endpoint string
+	Header   http.Header
+	Body     json.RawMessage
+}
+
+type dumpOptions struct {
+	adminSecretEnv string
+}
+
+// DumpOption the optional setting function of request dump rendering
+type DumpOption func(*dumpOptions)
+
+// DumpAdminSecretEnv renders the admin secret as a reference of the environment variable instead of the mask,
+// e.g. HASURA_GRAPHQL_ADMIN_SECRET
+func DumpAdminSecretEnv(name string) DumpOption {
+	return func(opts *dumpOptions) {
+		opts.adminSecretEnv = name
+	}
+}
+
+// CallRequestDump captures the masked dump of the request into the dump struct
+func CallRequestDump(dump *RequestDump) graphql.Option {
+	return callOption{
+		apply: func(opts *callOptions) {
+			opts.requestDump = dump
+		},
+	}
+}
+
+// RequestDumpFromError gets the masked dump of the request from the error of an operation
+// if the client enables WithResponseErrors
+func RequestDumpFromError(err error) (*RequestDump, bool) {
+	var responseErr *ResponseError
+	if !errors.As(err, &responseErr) || responseErr.Request == nil {
+		return nil, false
+	}
+	return responseErr.Request, true
+}
+
+// logRequestDump logs the curl command of the failed request in debug mode
+func (c *HasuraClient) logRequestDump(ctx context.Context, err error) {
+	state := getRequestState(ctx)
+	if state == nil || state.request == nil {
+		return
+	}
+	dump := state.request
+	logger := slog.Default()
+	if c.logger != nil {
+		logger = c.logger.logger
+	}
+	logger.LogAttrs(ctx, slog.LevelError, "hasura request dump",
+		slog.String("error", err.Error()),
+		slog.String("curl", dump.Curl(DumpAdminSecretEnv(DefaultAdminSecretEnv))),
+	)
+}
+
+// newRequestDump creates a masked dump of the http request. The request body is read from GetBody
+func newRequestDump(req *http.Request) *RequestDump {
+	dump := &RequestDump{
+		Method:   req.Method,
+		Endpoint: req.URL.String(),
+		Header:   req.Header.Clone(),
+	}
+	for _, name := range maskedDumpHeaders {
+		if dump.Header.Get(name) != "" {
+			dump.Header.Set(name, MaskedValue)
+		}
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			bs, _ := io.ReadAll(body)
+			dump.Body = bytes.TrimSpace(bs)
+			_ = body.Close()
+		}
+	}
+
+	return dump
+}
+
+// maskResponseHeaders replaces values of secret response headers with MaskedValue.
+// The header is cloned only if it has a secret header
+func maskResponseHeaders(header http.Header) http.Header {
+	masked := header
+	cloned := false
+	for _, name := range maskedResponseHeaders {
+		if header.Get(name) == "" {
+			continue
+		}
+		if !cloned {
+			masked = header.Clone()
+			cloned = true
+		}
+		masked.Set(name, MaskedValue)
+	}
+	return masked
+}
+
+// Curl renders the request as a runnable curl command
+func (rd RequestDump) Curl(options ...DumpOption) string {
+	var sb strings.Builder
+	sb.WriteString("curl -X ")
+	sb.WriteString(rd.Method)
+	sb.WriteString(" ")
+	sb.WriteString(shellQuote(rd.Endpoint))
+	rd.eachHeader(options, func(name string, value string, env string) {
+		sb.WriteString(" \\\n  -H ")
+		if env != "" {
+			// double quotes expand the environment variable
+			fmt.Fprintf(&sb, `"%s: $%s"`, name, env)
+			return
+		}
+		sb.WriteString(shellQuote(name + ": " + value))
+	})
+	if len(rd.Body) > 0 {
+		sb.WriteString(" \\\n  --data-raw ")
+		sb.WriteString(shellQuote(string(rd.Body)))
+	}
+
+	return sb.String()
+}
+
+// HTTPFile renders the request in the .http file format of REST clients
+func (rd RequestDump) HTTPFile(options ...DumpOption) string {
+	var sb strings.Builder
+	sb.WriteString(rd.Method)
+	sb.WriteString(" ")
+	sb.WriteString(rd.Endpoint)
+	sb.WriteString("\n")
+	rd.eachHeader(options, func(name string, value string, env string) {
+		if env != "" {
+			value = "{{" + env + "}}"
+		}
+		sb.WriteString(name)
+		sb.WriteString(": ")
+		sb.WriteString(value)
+		sb.WriteString("\n")
+	})
+	if len(rd.Body) > 0 {
+		sb.WriteString("\n")
+		var body bytes.Buffer
+		if err := json.Indent(&body, rd.Body, "", "  "); err == nil {
+			sb.Write(body.Bytes())
+		} else {
+			sb.Write(rd.Body)
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// eachHeader iterates headers in the sorted order, with the environment variable of the admin secret if configured
+func (rd RequestDump) eachHeader(options []DumpOption, fn func(name string, value string, env string)) {
+	opts := dumpOptions{}
+	for _, apply := range options {
+		apply(&opts)
+	}
+
+	names := make([]string, 0, len(rd.Header))
+	for name := range rd.Header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		env := ""
+		if opts.adminSecretEnv != "" && strings.EqualFold(name, XHasuraAdminSecret) {
+			env = opts.adminSecretEnv
+		}
+		for _, value := range rd.Header[name] {
+			fn(name, value, env)
+		}
+	}
+}
+
+// shellQuote quotes the value for POSIX shells
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
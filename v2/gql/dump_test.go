@@ -0,0 +1,93 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+	"gotest.tools/v3/assert"
+)
+
+func TestHasuraClient_RequestDump(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errors": [{"message": "field not found"}]}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	client := NewAdminClient(server.URL, "secret", WithDebug(true), WithLogger(logger), WithResponseErrors())
+	_, err := client.ExecRaw(context.Background(), "query GetUser($id: Int!) { user(id: $id) { name } }", map[string]any{"id": 1},
+		graphql.OperationName("GetUser"),
+		CallHeader("Authorization", "Bearer token"),
+		CallHeader("Cookie", "session=token"),
+		CallHeader(XRequestId, "abc"),
+		CallHeader("X-Note", "it's"),
+	)
+	assert.ErrorContains(t, err, "field not found")
+
+	dump, ok := RequestDumpFromError(err)
+	assert.Assert(t, ok)
+	assert.Equal(t, http.MethodPost, dump.Method)
+	assert.Equal(t, server.URL, dump.Endpoint)
+	assert.Equal(t, MaskedValue, dump.Header.Get(XHasuraAdminSecret))
+	assert.Equal(t, MaskedValue, dump.Header.Get("Authorization"))
+	assert.Equal(t, MaskedValue, dump.Header.Get("Cookie"))
+	assert.Assert(t, !strings.Contains(dump.Curl()+dump.HTTPFile(), "secret"))
+	assert.Assert(t, !strings.Contains(dump.Curl()+dump.HTTPFile(), "token"))
+
+	curl := dump.Curl(DumpAdminSecretEnv(DefaultAdminSecretEnv))
+	assert.Assert(t, strings.HasPrefix(curl, "curl -X POST '"+server.URL+"'"))
+	assert.Assert(t, strings.Contains(curl, `-H "X-Hasura-Admin-Secret: $HASURA_GRAPHQL_ADMIN_SECRET"`))
+	assert.Assert(t, strings.Contains(curl, `-H 'X-Note: it'\''s'`))
+	assert.Assert(t, strings.Contains(curl, `--data-raw '{"query":"query GetUser($id: Int!) { user(id: $id) { name } }","variables":{"id":1},"operationName":"GetUser"}'`))
+
+	httpFile := dump.HTTPFile(DumpAdminSecretEnv("ADMIN_SECRET"))
+	assert.Assert(t, strings.HasPrefix(httpFile, "POST "+server.URL+"\n"))
+	assert.Assert(t, strings.Contains(httpFile, "X-Hasura-Admin-Secret: {{ADMIN_SECRET}}\n"))
+	assert.Assert(t, strings.Contains(httpFile, "X-Request-Id: abc\n"))
+	assert.Assert(t, strings.HasSuffix(httpFile, "\n\n{\n  \"query\": \"query GetUser($id: Int!) { user(id: $id) { name } }\",\n  \"variables\": {\n    \"id\": 1\n  },\n  \"operationName\": \"GetUser\"\n}\n"))
+
+	var record map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		assert.NilError(t, json.Unmarshal([]byte(line), &record))
+		if record["msg"] == "hasura request dump" {
+			break
+		}
+	}
+	assert.Equal(t, curl, record["curl"])
+
+	var successDump RequestDump
+	_, _ = NewHasuraClient(server.URL).ExecRaw(context.Background(), "query { foo }", nil, CallRequestDump(&successDump))
+	assert.Equal(t, server.URL, successDump.Endpoint)
+}
+
+func TestRequestState_DumpRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/graphql", strings.NewReader(`{"query": "query { foo }"}`))
+	for _, tc := range []struct {
+		name    string
+		client  *HasuraClient
+		options []graphql.Option
+		dumped  bool
+	}{
+		{"disabled", NewAdminClient("http://localhost:8080/v1/graphql", "secret"), nil, false},
+		{"debug", NewAdminClient("http://localhost:8080/v1/graphql", "secret", WithDebug(true)), nil, true},
+		{"response errors", NewAdminClient("http://localhost:8080/v1/graphql", "secret", WithResponseErrors()), nil, true},
+		{"call dump", NewAdminClient("http://localhost:8080/v1/graphql", "secret"), []graphql.Option{CallRequestDump(&RequestDump{})}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, _, err := tc.client.prepareRequest(context.Background(), tc.options)
+			assert.NilError(t, err)
+			state := getRequestState(ctx)
+			state.recordRequest(req)
+			assert.Assert(t, state.sent)
+			assert.Equal(t, tc.dumped, state.request != nil)
+		})
+	}
+}
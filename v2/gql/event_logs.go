@@ -0,0 +1,311 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hgiasac/hasura-utils/v2/types"
+)
+
+// EventLogStatus represents the status filter of pg_get_event_logs
+type EventLogStatus string
+
+const (
+	EventLogPending   EventLogStatus = "pending"
+	EventLogProcessed EventLogStatus = "processed"
+	EventLogAll       EventLogStatus = "all"
+)
+
+const defaultSourceName = "default"
+
+// GetEventLogsInput represents the arguments of pg_get_event_logs
+type GetEventLogsInput struct {
+	Name   string         `json:"name"`
+	Source string         `json:"source,omitempty"`
+	Status EventLogStatus `json:"status,omitempty"`
+	Limit  int            `json:"limit,omitempty"`
+	Offset int            `json:"offset,omitempty"`
+}
+
+// GetEventInvocationLogsInput represents the arguments of pg_get_event_invocation_logs
+type GetEventInvocationLogsInput struct {
+	Name   string `json:"name"`
+	Source string `json:"source,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+}
+
+// GetEventByIDInput represents the arguments of pg_get_event_by_id
+type GetEventByIDInput struct {
+	EventID             string `json:"event_id"`
+	Source              string `json:"source,omitempty"`
+	InvocationLogLimit  int    `json:"invocation_log_limit,omitempty"`
+	InvocationLogOffset int    `json:"invocation_log_offset,omitempty"`
+}
+
+// EventLog represents a row of the event trigger log
+type EventLog struct {
+	ID          string           `json:"id"`
+	SchemaName  string           `json:"schema_name"`
+	TableName   string           `json:"table_name"`
+	TriggerName string           `json:"trigger_name"`
+	Payload     json.RawMessage  `json:"payload"`
+	Delivered   bool             `json:"delivered"`
+	Error       bool             `json:"error"`
+	Tries       int              `json:"tries"`
+	CreatedAt   types.Timestamp  `json:"created_at"`
+	Locked      *types.Timestamp `json:"locked,omitempty"`
+	NextRetryAt *types.Timestamp `json:"next_retry_at,omitempty"`
+	Archived    bool             `json:"archived"`
+}
+
+// EventInvocationLog represents a delivery attempt of an event trigger
+type EventInvocationLog struct {
+	ID          string          `json:"id"`
+	TriggerName string          `json:"trigger_name"`
+	EventID     string          `json:"event_id"`
+	Status      int             `json:"status"`
+	Request     json.RawMessage `json:"request"`
+	Response    json.RawMessage `json:"response"`
+	CreatedAt   types.Timestamp `json:"created_at"`
+}
+
+// EventWithInvocationLogs represents the response of pg_get_event_by_id
+type EventWithInvocationLogs struct {
+	Event          EventLog             `json:"event"`
+	InvocationLogs []EventInvocationLog `json:"invocation_logs"`
+}
+
+// GetEventLogs lists the event logs of an event trigger
+func (c *HasuraClient) GetEventLogs(ctx context.Context, input GetEventLogsInput) ([]EventLog, error) {
+	if input.Source == "" {
+		input.Source = defaultSourceName
+	}
+	var result []EventLog
+	if err := c.Metadata(ctx, MetadataRequest{
+		Type: "pg_get_event_logs",
+		Args: input,
+	}, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetEventInvocationLogs lists the delivery attempts of an event trigger
+func (c *HasuraClient) GetEventInvocationLogs(ctx context.Context, input GetEventInvocationLogsInput) ([]EventInvocationLog, error) {
+	if input.Source == "" {
+		input.Source = defaultSourceName
+	}
+	var result []EventInvocationLog
+	if err := c.Metadata(ctx, MetadataRequest{
+		Type: "pg_get_event_invocation_logs",
+		Args: input,
+	}, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetEventByID gets an event log and its delivery attempts
+func (c *HasuraClient) GetEventByID(ctx context.Context, input GetEventByIDInput) (*EventWithInvocationLogs, error) {
+	if input.Source == "" {
+		input.Source = defaultSourceName
+	}
+	var result EventWithInvocationLogs
+	if err := c.Metadata(ctx, MetadataRequest{
+		Type: "pg_get_event_by_id",
+		Args: input,
+	}, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// RedeliverEvent redelivers an existing event by id
+func (c *HasuraClient) RedeliverEvent(ctx context.Context, eventID string) error {
+	return c.Metadata(ctx, MetadataRequest{
+		Type: "redeliver_event",
+		Args: map[string]any{
+			"event_id": eventID,
+		},
+	}, nil)
+}
+
+// RedeliverFailedEventsInput represents the filter and settings of the bulk redelivery
+type RedeliverFailedEventsInput struct {
+	Source       string
+	TriggerNames []string
+	// Since and Until limit the creation time window of events. Zero values mean unbounded
+	Since time.Time
+	Until time.Time
+	// StatusCodes filters events by the HTTP status of the latest delivery attempt.
+	// All failed events are selected if empty
+	StatusCodes []int
+	// Concurrency limits the number of concurrent redeliveries, default 5
+	Concurrency int
+	// PageSize the number of event logs fetched per request, default 100
+	PageSize int
+	// DryRun only reports matched events without redelivering them
+	DryRun bool
+}
+
+// RedeliveredEvent represents a matched event of the bulk redelivery
+type RedeliveredEvent struct {
+	EventID     string
+	TriggerName string
+	CreatedAt   time.Time
+	Tries       int
+	LastStatus  int
+	Redelivered bool
+	Error       error
+}
+
+// RedeliverReport represents the result of the bulk redelivery
+type RedeliverReport struct {
+	DryRun bool
+	Events []RedeliveredEvent
+}
+
+// Succeeded returns the number of successfully redelivered events
+func (rr RedeliverReport) Succeeded() int {
+	count := 0
+	for _, e := range rr.Events {
+		if e.Redelivered {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed returns the number of events that couldn't be redelivered
+func (rr RedeliverReport) Failed() int {
+	count := 0
+	for _, e := range rr.Events {
+		if e.Error != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// RedeliverFailedEvents finds failed events of triggers in the time window and redelivers them with a concurrency limit.
+// Event logs are expected to be returned in descending order of creation time
+func (c *HasuraClient) RedeliverFailedEvents(ctx context.Context, input RedeliverFailedEventsInput) (*RedeliverReport, error) {
+	if len(input.TriggerNames) == 0 {
+		return nil, errors.New("at least one trigger name is required")
+	}
+	if input.Concurrency <= 0 {
+		input.Concurrency = 5
+	}
+	if input.PageSize <= 0 {
+		input.PageSize = 100
+	}
+	if !input.DryRun {
+		// fail before the search instead of reporting every event of read-only clients as failed
+		if err := c.checkReadOnlyMetadata(MetadataRequest{Type: "redeliver_event"}); err != nil {
+			return nil, err
+		}
+	}
+
+	var events []RedeliveredEvent
+	for _, triggerName := range input.TriggerNames {
+		matched, err := c.findFailedEvents(ctx, triggerName, input)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, matched...)
+	}
+
+	report := &RedeliverReport{
+		DryRun: input.DryRun,
+		Events: events,
+	}
+	if input.DryRun {
+		return report, nil
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, input.Concurrency)
+	for i := range report.Events {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return report, ctx.Err()
+		}
+		wg.Add(1)
+		go func(e *RedeliveredEvent) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			e.Error = c.RedeliverEvent(ctx, e.EventID)
+			e.Redelivered = e.Error == nil
+		}(&report.Events[i])
+	}
+	wg.Wait()
+
+	return report, nil
+}
+
+func (c *HasuraClient) findFailedEvents(ctx context.Context, triggerName string, input RedeliverFailedEventsInput) ([]RedeliveredEvent, error) {
+	var results []RedeliveredEvent
+	for offset := 0; ; offset += input.PageSize {
+		logs, err := c.GetEventLogs(ctx, GetEventLogsInput{
+			Name:   triggerName,
+			Source: input.Source,
+			Status: EventLogProcessed,
+			Limit:  input.PageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, log := range logs {
+			if !log.Error || log.Archived ||
+				(!input.Since.IsZero() && log.CreatedAt.Before(input.Since)) ||
+				(!input.Until.IsZero() && log.CreatedAt.After(input.Until)) {
+				continue
+			}
+
+			item := RedeliveredEvent{
+				EventID:     log.ID,
+				TriggerName: triggerName,
+				CreatedAt:   log.CreatedAt.Time,
+				Tries:       log.Tries,
+			}
+
+			if len(input.StatusCodes) > 0 {
+				event, err := c.GetEventByID(ctx, GetEventByIDInput{
+					EventID:            log.ID,
+					Source:             input.Source,
+					InvocationLogLimit: 1,
+				})
+				if err != nil {
+					return nil, err
+				}
+				if len(event.InvocationLogs) > 0 {
+					item.LastStatus = event.InvocationLogs[0].Status
+				}
+				if !slices.Contains(input.StatusCodes, item.LastStatus) {
+					continue
+				}
+			}
+
+			results = append(results, item)
+		}
+
+		if len(logs) < input.PageSize ||
+			(!input.Since.IsZero() && logs[len(logs)-1].CreatedAt.Before(input.Since)) {
+			return results, nil
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRedeliverFailedEvents(t *testing.T) {
+	var mu sync.Mutex
+	var redelivered []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Type string         `json:"type"`
+			Args map[string]any `json:"args"`
+		}
+		assert.Check(t, json.NewDecoder(r.Body).Decode(&body))
+		switch body.Type {
+		case "pg_get_event_logs":
+			if body.Args["offset"] != nil {
+				_, _ = w.Write([]byte(`[]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[
+				{"id": "1", "trigger_name": "foo", "error": true, "tries": 3, "created_at": "2024-05-03T10:48:43.853829", "next_retry_at": null, "locked": null},
+				{"id": "2", "trigger_name": "foo", "error": false, "delivered": true, "created_at": "2024-05-02T00:00:00.000001"},
+				{"id": "3", "trigger_name": "foo", "error": true, "created_at": "2024-05-02T08:15:00.5"},
+				{"id": "4", "trigger_name": "foo", "error": true, "created_at": "2024-04-01T00:00:00Z"}
+			]`))
+		case "pg_get_event_by_id":
+			status := 500
+			if body.Args["event_id"] == "3" {
+				status = 404
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"event":           map[string]any{"id": body.Args["event_id"], "created_at": "2024-05-03T10:48:43.853829"},
+				"invocation_logs": []map[string]any{{"status": status, "created_at": "2024-05-03T10:48:44.120000"}},
+			})
+		case "redeliver_event":
+			mu.Lock()
+			redelivered = append(redelivered, body.Args["event_id"].(string))
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"message": "success"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewAdminClient(server.URL+"/v1/graphql", "secret")
+	input := RedeliverFailedEventsInput{
+		TriggerNames: []string{"foo"},
+		Since:        time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		StatusCodes:  []int{500},
+		PageSize:     4,
+		DryRun:       true,
+	}
+
+	report, err := client.RedeliverFailedEvents(context.Background(), input)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(report.Events))
+	assert.Equal(t, "1", report.Events[0].EventID)
+	assert.Equal(t, 500, report.Events[0].LastStatus)
+	assert.Assert(t, report.Events[0].CreatedAt.Equal(time.Date(2024, 5, 3, 10, 48, 43, 853829000, time.UTC)), report.Events[0].CreatedAt)
+	assert.Equal(t, 0, len(redelivered))
+
+	input.DryRun = false
+	input.StatusCodes = nil
+	report, err = client.RedeliverFailedEvents(context.Background(), input)
+	assert.NilError(t, err)
+	assert.Equal(t, 2, report.Succeeded())
+	assert.Equal(t, 0, report.Failed())
+	assert.Equal(t, 2, len(redelivered))
+}
@@ -0,0 +1,96 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+var errRootFieldRequired = errors.New("root field is required")
+
+// RootField selects the root field that generic helpers decode into T instead of the whole response.
+// The field is rendered as the graphql tag of the root field, e.g. users(where: $where) for Query and Mutate,
+// or the response key of the root field, e.g. users or the alias, for Exec.
+// It's a per-call option that HasuraClient methods ignore
+func RootField(field string) graphql.Option {
+	return callOption{
+		apply: func(opts *callOptions) {
+			opts.rootField = &field
+		},
+	}
+}
+
+// Query builds the query from T, or from the root field of type T if the RootField option is set,
+// and returns the decoded response. It has the tracing and session behavior of HasuraClient.Query
+func Query[T any](ctx context.Context, c *HasuraClient, variables map[string]any, options ...graphql.Option) (T, error) {
+	return run[T](options, func(result any) error {
+		return c.Query(ctx, result, variables, options...)
+	})
+}
+
+// Mutate builds the mutation from T, or from the root field of type T if the RootField option is set,
+// and returns the decoded response. It has the tracing and session behavior of HasuraClient.Mutate
+func Mutate[T any](ctx context.Context, c *HasuraClient, variables map[string]any, options ...graphql.Option) (T, error) {
+	return run[T](options, func(result any) error {
+		return c.Mutate(ctx, result, variables, options...)
+	})
+}
+
+// Exec executes the raw GraphQL document and returns the decoded response,
+// or the decoded root field if the RootField option is set. It has the tracing and session behavior of HasuraClient.Exec
+func Exec[T any](ctx context.Context, c *HasuraClient, query string, variables map[string]any, options ...graphql.Option) (T, error) {
+	return run[T](options, func(result any) error {
+		return c.Exec(ctx, query, result, variables, options...)
+	})
+}
+
+// run decodes the response into T directly, or into a struct that wraps T in the selected root field
+func run[T any](options []graphql.Option, fn func(result any) error) (T, error) {
+	var result T
+	field, err := getRootField(options)
+	if err != nil {
+		return result, err
+	}
+	if field == "" {
+		err = fn(&result)
+		return result, err
+	}
+
+	wrapper := reflect.New(reflect.StructOf([]reflect.StructField{
+		{
+			Name: "Field",
+			Type: reflect.TypeOf((*T)(nil)).Elem(),
+			Tag:  reflect.StructTag(fmt.Sprintf("graphql:%q", field)),
+		},
+	}))
+	err = fn(wrapper.Interface())
+	if value, ok := wrapper.Elem().Field(0).Interface().(T); ok {
+		result = value
+	}
+	return result, err
+}
+
+// getRootField gets the root field selector from per-call options.
+// The option is stripped by the client with other per-call options
+func getRootField(options []graphql.Option) (string, error) {
+	opts := callOptions{
+		headers: SessionVariables{},
+	}
+	for _, opt := range options {
+		if co, ok := opt.(callOption); ok {
+			co.apply(&opts)
+		}
+	}
+	if opts.rootField == nil {
+		return "", nil
+	}
+	field := strings.TrimSpace(*opts.rootField)
+	if field == "" {
+		return "", errRootFieldRequired
+	}
+	return field, nil
+}
@@ -0,0 +1,79 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+	"gotest.tools/v3/assert"
+)
+
+func TestGenericHelpers(t *testing.T) {
+	var requestBody struct {
+		Query string `json:"query"`
+	}
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &requestBody)
+		headers = r.Header.Clone()
+		if strings.Contains(requestBody.Query, "total") {
+			_, _ = w.Write([]byte(`{"data": {"total": {"aggregate": {"count": 1}}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"users": [{"id": 1, "name": "foo"}]}}`))
+	}))
+	defer server.Close()
+
+	type user struct {
+		ID   int    `graphql:"id" json:"id"`
+		Name string `graphql:"name" json:"name"`
+	}
+	client, err := NewAdminClient(server.URL, "secret").AsRole("user", "1")
+	assert.NilError(t, err)
+
+	users, err := Query[[]user](context.Background(), client, map[string]any{
+		"limit": 10,
+	}, RootField("users(limit: $limit)"), graphql.OperationName("GetUsers"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []user{{ID: 1, Name: "foo"}}, users)
+	assert.Equal(t, "query GetUsers($limit:Int!){users(limit: $limit){id,name}}", requestBody.Query)
+	assert.Equal(t, "user", headers.Get(XHasuraRole))
+	assert.Equal(t, "1", headers.Get(XHasuraUserID))
+
+	type usersQuery struct {
+		Users []user `graphql:"users"`
+	}
+	result, err := Query[usersQuery](context.Background(), client, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, "foo", result.Users[0].Name)
+
+	count, err := Exec[struct {
+		Aggregate struct {
+			Count int `graphql:"count"`
+		} `graphql:"aggregate"`
+	}](context.Background(), client, "query { total: users_aggregate { aggregate { count } } }", nil, RootField("total"))
+	assert.NilError(t, err)
+	assert.Equal(t, 1, count.Aggregate.Count)
+
+	users, err = Exec[[]user](context.Background(), client, "query { users { id name } }", nil, RootField("users"))
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(users))
+
+	_, err = Query[[]user](context.Background(), client, nil, RootField(" "))
+	assert.ErrorIs(t, err, errRootFieldRequired)
+
+	// client methods strip the option with other per-call options
+	var raw struct {
+		Users []user `graphql:"users"`
+	}
+	assert.NilError(t, client.Query(context.Background(), &raw, nil, RootField("users")))
+	_, err = client.QueryRaw(context.Background(), &raw, nil, RootField("users"))
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(raw.Users))
+}
@@ -0,0 +1,662 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgiasac/hasura-utils/utils"
+)
+
+const defaultJWTClaimsNamespace = "https://hasura.io/jwt/claims"
+
+// JWT error codes are compatible with Hasura
+const (
+	ErrCodeInvalidJWT       = "invalid-jwt"
+	ErrCodeJWTInvalidClaims = "jwt-invalid-claims"
+	ErrCodeJWTMissingRole   = "jwt-missing-role-claims"
+	ErrCodeAccessDenied     = "access-denied"
+)
+
+var (
+	ErrJWTMissing       = &JWTError{Code: ErrCodeInvalidJWT, Message: "missing authorization token"}
+	ErrJWTInvalid       = &JWTError{Code: ErrCodeInvalidJWT, Message: "invalid token"}
+	ErrJWTExpired       = &JWTError{Code: ErrCodeInvalidJWT, Message: "token expired"}
+	ErrJWTInvalidClaims = &JWTError{Code: ErrCodeJWTInvalidClaims, Message: "invalid claims"}
+	ErrJWTMissingRole   = &JWTError{Code: ErrCodeJWTMissingRole, Message: "missing role claims"}
+	ErrJWTRoleDenied    = &JWTError{Code: ErrCodeAccessDenied, Message: "the requested role is not in the allowed roles"}
+)
+
+// JWTError represents a typed JWT verification error.
+// Errors are matched with errors.Is by the code and the base message
+type JWTError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+// Error implements the error interface
+func (je *JWTError) Error() string {
+	if je.Err == nil {
+		return fmt.Sprintf("%s: %s", je.Code, je.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", je.Code, je.Message, je.Err)
+}
+
+// Unwrap returns the cause error
+func (je *JWTError) Unwrap() error {
+	return je.Err
+}
+
+// Is checks if the target error has the same code and message
+func (je *JWTError) Is(target error) bool {
+	t, ok := target.(*JWTError)
+	return ok && t.Code == je.Code && t.Message == je.Message
+}
+
+func (je *JWTError) wrap(err error) *JWTError {
+	return &JWTError{Code: je.Code, Message: je.Message, Err: err}
+}
+
+// JWTClaimsFormat represents the format of Hasura claims in the token
+type JWTClaimsFormat string
+
+const (
+	JWTClaimsFormatJSON            JWTClaimsFormat = "json"
+	JWTClaimsFormatStringifiedJSON JWTClaimsFormat = "stringified_json"
+)
+
+// JWTHeaderConfig represents the location of the token in the request
+type JWTHeaderConfig struct {
+	// Type is one of Authorization, Cookie or CustomHeader
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// JWTClaimMapValue represents a value of claims_map, it is either a JSON path with an optional default value or a literal value
+type JWTClaimMapValue struct {
+	Path    string `json:"path,omitempty"`
+	Default any    `json:"default,omitempty"`
+	Value   any    `json:"-"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (cmv *JWTClaimMapValue) UnmarshalJSON(b []byte) error {
+	var obj struct {
+		Path    *string `json:"path"`
+		Default any     `json:"default"`
+	}
+	if err := json.Unmarshal(b, &obj); err == nil && obj.Path != nil {
+		cmv.Path = *obj.Path
+		cmv.Default = obj.Default
+		return nil
+	}
+
+	return json.Unmarshal(b, &cmv.Value)
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (cmv JWTClaimMapValue) MarshalJSON() ([]byte, error) {
+	if cmv.Path == "" {
+		return json.Marshal(cmv.Value)
+	}
+	type claimPath JWTClaimMapValue
+	return json.Marshal(claimPath(cmv))
+}
+
+// JWTAudience represents the audience config that can be a string or an array of strings
+type JWTAudience []string
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (ja *JWTAudience) UnmarshalJSON(b []byte) error {
+	var values []string
+	if err := json.Unmarshal(b, &values); err == nil {
+		*ja = values
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	*ja = []string{value}
+	return nil
+}
+
+// JWTConfig mirrors the HASURA_GRAPHQL_JWT_SECRET config.
+// JWKFile is an extension that loads the key set from a local file
+type JWTConfig struct {
+	Type                string                      `json:"type,omitempty"`
+	Key                 string                      `json:"key,omitempty"`
+	JWKURL              string                      `json:"jwk_url,omitempty"`
+	JWKFile             string                      `json:"jwk_file,omitempty"`
+	ClaimsNamespace     string                      `json:"claims_namespace,omitempty"`
+	ClaimsNamespacePath string                      `json:"claims_namespace_path,omitempty"`
+	ClaimsFormat        JWTClaimsFormat             `json:"claims_format,omitempty"`
+	ClaimsMap           map[string]JWTClaimMapValue `json:"claims_map,omitempty"`
+	Audience            JWTAudience                 `json:"audience,omitempty"`
+	Issuer              string                      `json:"issuer,omitempty"`
+	AllowedSkew         int                         `json:"allowed_skew,omitempty"`
+	Header              *JWTHeaderConfig            `json:"header,omitempty"`
+}
+
+// ParseJWTConfig parses the JSON string of HASURA_GRAPHQL_JWT_SECRET
+func ParseJWTConfig(input string) (*JWTConfig, error) {
+	var config JWTConfig
+	if err := json.Unmarshal([]byte(input), &config); err != nil {
+		return nil, fmt.Errorf("invalid JWT config: %w", err)
+	}
+	return &config, nil
+}
+
+type jwtOptions struct {
+	httpClient      *http.Client
+	refreshInterval time.Duration
+	now             func() time.Time
+}
+
+// JWTOption the optional setting function of the JWT parser
+type JWTOption func(*jwtOptions)
+
+// WithJWKHTTPClient sets the http client to fetch the JWKS URL
+func WithJWKHTTPClient(httpClient *http.Client) JWTOption {
+	return func(opts *jwtOptions) {
+		opts.httpClient = httpClient
+	}
+}
+
+// WithJWKRefreshInterval sets the refresh interval of the JWKS if the response doesn't have cache headers
+func WithJWKRefreshInterval(interval time.Duration) JWTOption {
+	return func(opts *jwtOptions) {
+		opts.refreshInterval = interval
+	}
+}
+
+// JWTParser verifies JWT tokens and computes session variables with the same semantics as Hasura
+type JWTParser struct {
+	config    JWTConfig
+	staticKey any
+	jwks      *jwkSource
+	now       func() time.Time
+}
+
+// NewJWTParser creates a JWT parser from the config
+func NewJWTParser(config JWTConfig, options ...JWTOption) (*JWTParser, error) {
+	opts := jwtOptions{
+		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		refreshInterval: time.Hour,
+		now:             time.Now,
+	}
+	for _, apply := range options {
+		apply(&opts)
+	}
+
+	parser := &JWTParser{
+		config: config,
+		now:    opts.now,
+	}
+	switch {
+	case config.Key != "":
+		if config.Type == "" {
+			return nil, errors.New("the JWT algorithm type is required with the key")
+		}
+		key, err := parseJWTKey(config.Type, config.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT key: %w", err)
+		}
+		parser.staticKey = key
+	case config.JWKURL != "" || config.JWKFile != "":
+		parser.jwks = &jwkSource{
+			url:             config.JWKURL,
+			file:            config.JWKFile,
+			httpClient:      opts.httpClient,
+			refreshInterval: opts.refreshInterval,
+			now:             opts.now,
+		}
+	default:
+		return nil, errors.New("either key, jwk_url or jwk_file is required")
+	}
+
+	if config.ClaimsNamespace != "" && config.ClaimsNamespacePath != "" {
+		return nil, errors.New("claims_namespace and claims_namespace_path are mutually exclusive")
+	}
+
+	return parser, nil
+}
+
+// Authenticate extracts the token from the request and returns session variables.
+// It can be used as the AuthenticateFunc of the auth webhook handler
+func (p *JWTParser) Authenticate(ctx context.Context, r *http.Request) (SessionVariables, error) {
+	token, err := p.extractToken(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.Parse(ctx, token, r.Header.Get(XHasuraRole))
+}
+
+// Parse verifies the token and returns session variables. The requested role overrides the default role
+// if it exists in the allowed roles
+func (p *JWTParser) Parse(ctx context.Context, token string, requestedRole string) (SessionVariables, error) {
+	claims, err := p.verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.validateStandardClaims(claims); err != nil {
+		return nil, err
+	}
+
+	hasuraClaims, err := p.getHasuraClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildJWTSessionVariables(hasuraClaims, requestedRole)
+}
+
+func (p *JWTParser) extractToken(r *http.Request) (string, error) {
+	header := p.config.Header
+	if header == nil || strings.EqualFold(header.Type, "Authorization") {
+		value := r.Header.Get("Authorization")
+		if value == "" {
+			return "", ErrJWTMissing
+		}
+		parts := strings.SplitN(value, " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+			return "", ErrJWTInvalid.wrap(errors.New("malformed Authorization header"))
+		}
+		return strings.TrimSpace(parts[1]), nil
+	}
+
+	switch header.Type {
+	case "Cookie":
+		cookie, err := r.Cookie(header.Name)
+		if err != nil || cookie.Value == "" {
+			return "", ErrJWTMissing
+		}
+		return cookie.Value, nil
+	case "CustomHeader":
+		value := r.Header.Get(header.Name)
+		if value == "" {
+			return "", ErrJWTMissing
+		}
+		return value, nil
+	default:
+		return "", fmt.Errorf("unsupported JWT header type %s", header.Type)
+	}
+}
+
+func (p *JWTParser) verify(ctx context.Context, token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTInvalid.wrap(errors.New("malformed token"))
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, ErrJWTInvalid.wrap(err)
+	}
+	if header.Alg == "" || header.Alg == "none" {
+		return nil, ErrJWTInvalid.wrap(errors.New("unsigned tokens are not allowed"))
+	}
+	if p.config.Type != "" && header.Alg != p.config.Type {
+		return nil, ErrJWTInvalid.wrap(fmt.Errorf("unexpected algorithm %s", header.Alg))
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTInvalid.wrap(err)
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+
+	if err := p.verifySignature(ctx, header.Alg, header.Kid, signingInput, signature); err != nil {
+		return nil, ErrJWTInvalid.wrap(err)
+	}
+
+	var claims map[string]any
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, ErrJWTInvalid.wrap(err)
+	}
+
+	return claims, nil
+}
+
+func (p *JWTParser) verifySignature(ctx context.Context, alg string, kid string, signingInput []byte, signature []byte) error {
+	if p.jwks == nil {
+		return verifyJWTSignature(alg, p.staticKey, signingInput, signature)
+	}
+
+	keys, err := p.jwks.getKeys(ctx, false)
+	if err != nil {
+		return err
+	}
+	key := findJWTKey(keys, alg, kid)
+	if key == nil {
+		// the key set may be rotated
+		keys, err = p.jwks.getKeys(ctx, true)
+		if err != nil {
+			return err
+		}
+		key = findJWTKey(keys, alg, kid)
+	}
+	if key == nil {
+		return fmt.Errorf("key %s not found", kid)
+	}
+
+	return verifyJWTSignature(alg, key.key, signingInput, signature)
+}
+
+func findJWTKey(keys []jwtKey, alg string, kid string) *jwtKey {
+	for i, k := range keys {
+		if (kid == "" || k.kid == kid) && (k.alg == "" || k.alg == alg) {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+func (p *JWTParser) validateStandardClaims(claims map[string]any) error {
+	now := p.now()
+	skew := time.Duration(p.config.AllowedSkew) * time.Second
+
+	if exp, ok, err := getNumericDateClaim(claims, "exp"); err != nil {
+		return ErrJWTInvalidClaims.wrap(err)
+	} else if ok && !now.Before(exp.Add(skew)) {
+		return ErrJWTExpired
+	}
+
+	if nbf, ok, err := getNumericDateClaim(claims, "nbf"); err != nil {
+		return ErrJWTInvalidClaims.wrap(err)
+	} else if ok && now.Add(skew).Before(nbf) {
+		return ErrJWTInvalidClaims.wrap(errors.New("token is not valid yet"))
+	}
+
+	if p.config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != p.config.Issuer {
+			return ErrJWTInvalidClaims.wrap(errors.New("invalid issuer"))
+		}
+	}
+
+	if len(p.config.Audience) > 0 {
+		var audiences []string
+		switch aud := claims["aud"].(type) {
+		case string:
+			audiences = []string{aud}
+		case []any:
+			for _, a := range aud {
+				if s, ok := a.(string); ok {
+					audiences = append(audiences, s)
+				}
+			}
+		}
+		if !slices.ContainsFunc(audiences, func(aud string) bool {
+			return slices.Contains(p.config.Audience, aud)
+		}) {
+			return ErrJWTInvalidClaims.wrap(errors.New("invalid audience"))
+		}
+	}
+
+	return nil
+}
+
+// getHasuraClaims returns the Hasura claims object from the token claims with the claims map or namespace
+func (p *JWTParser) getHasuraClaims(claims map[string]any) (map[string]any, error) {
+	if len(p.config.ClaimsMap) > 0 {
+		result := make(map[string]any)
+		for key, mapValue := range p.config.ClaimsMap {
+			if mapValue.Path == "" {
+				result[strings.ToLower(key)] = mapValue.Value
+				continue
+			}
+			value, ok, err := lookupJSONPath(claims, mapValue.Path)
+			if err != nil {
+				return nil, ErrJWTInvalidClaims.wrap(err)
+			}
+			if !ok {
+				if mapValue.Default == nil {
+					return nil, ErrJWTInvalidClaims.wrap(fmt.Errorf("claim %s not found at %s", key, mapValue.Path))
+				}
+				value = mapValue.Default
+			}
+			result[strings.ToLower(key)] = value
+		}
+		return result, nil
+	}
+
+	var value any
+	var ok bool
+	if p.config.ClaimsNamespacePath != "" {
+		var err error
+		value, ok, err = lookupJSONPath(claims, p.config.ClaimsNamespacePath)
+		if err != nil {
+			return nil, ErrJWTInvalidClaims.wrap(err)
+		}
+	} else {
+		namespace := p.config.ClaimsNamespace
+		if namespace == "" {
+			namespace = defaultJWTClaimsNamespace
+		}
+		value, ok = claims[namespace]
+	}
+	if !ok {
+		return nil, ErrJWTInvalidClaims.wrap(errors.New("hasura claims not found"))
+	}
+
+	if p.config.ClaimsFormat == JWTClaimsFormatStringifiedJSON {
+		s, isString := value.(string)
+		if !isString {
+			return nil, ErrJWTInvalidClaims.wrap(errors.New("hasura claims must be a stringified JSON"))
+		}
+		var decoded any
+		if err := unmarshalJSONNumber([]byte(s), &decoded); err != nil {
+			return nil, ErrJWTInvalidClaims.wrap(err)
+		}
+		value = decoded
+	}
+
+	obj, isObject := value.(map[string]any)
+	if !isObject {
+		return nil, ErrJWTInvalidClaims.wrap(errors.New("hasura claims must be an object"))
+	}
+
+	result := make(map[string]any)
+	for k, v := range obj {
+		result[strings.ToLower(k)] = v
+	}
+	return result, nil
+}
+
+func buildJWTSessionVariables(claims map[string]any, requestedRole string) (SessionVariables, error) {
+	allowedRoles, err := toStringSlice(claims[XHasuraAllowedRoles])
+	if err != nil || len(allowedRoles) == 0 {
+		return nil, ErrJWTMissingRole.wrap(fmt.Errorf("%s is required", XHasuraAllowedRoles))
+	}
+	defaultRole, _ := claims[XHasuraDefaultRole].(string)
+	if defaultRole == "" {
+		return nil, ErrJWTMissingRole.wrap(fmt.Errorf("%s is required", XHasuraDefaultRole))
+	}
+
+	role := defaultRole
+	if requestedRole != "" {
+		if !slices.Contains(allowedRoles, requestedRole) {
+			return nil, ErrJWTRoleDenied
+		}
+		role = requestedRole
+	}
+
+	result := SessionVariables{}
+	for key, value := range claims {
+		if !strings.HasPrefix(key, "x-hasura-") {
+			continue
+		}
+		s, err := sessionValueToString(value)
+		if err != nil {
+			return nil, ErrJWTInvalidClaims.wrap(fmt.Errorf("%s: %w", key, err))
+		}
+		result[key] = s
+	}
+	result[XHasuraRole] = role
+
+	return result, nil
+}
+
+// sessionValueToString encodes JSON claim values to session variable strings, arrays use the Postgres array format
+func sessionValueToString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	case []any, []string:
+		values, err := toStringSlice(v)
+		if err != nil {
+			return "", err
+		}
+		return utils.EncodePostgresArray(values), nil
+	case nil:
+		return "", errors.New("null value")
+	default:
+		bs, err := json.Marshal(v)
+		return string(bs), err
+	}
+}
+
+func toStringSlice(value any) ([]string, error) {
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case []any:
+		results := make([]string, len(v))
+		for i, item := range v {
+			s, err := sessionValueToString(item)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = s
+		}
+		return results, nil
+	case string:
+		// stringified Postgres array
+		return utils.DecodePostgresArray(v)
+	default:
+		return nil, fmt.Errorf("expected an array, got %T", value)
+	}
+}
+
+func getNumericDateClaim(claims map[string]any, key string) (time.Time, bool, error) {
+	value, ok := claims[key]
+	if !ok || value == nil {
+		return time.Time{}, false, nil
+	}
+	number, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("%s must be a numeric date", key)
+	}
+	seconds, err := number.Int64()
+	if err != nil {
+		// numeric dates may have fractional seconds
+		f, err := number.Float64()
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%s must be a numeric date", key)
+		}
+		seconds = int64(f)
+	}
+	return time.Unix(seconds, 0), true, nil
+}
+
+func decodeJWTSegment(segment string, target any) error {
+	bs, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	return unmarshalJSONNumber(bs, target)
+}
+
+// unmarshalJSONNumber decodes numbers as json.Number, so that large integer claims keep their precision
+func unmarshalJSONNumber(bs []byte, target any) error {
+	decoder := json.NewDecoder(bytes.NewReader(bs))
+	decoder.UseNumber()
+	if err := decoder.Decode(target); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
+// lookupJSONPath gets the value of a simple JSON path such as $.a.b, $['a.b'].c or $.a[0]
+func lookupJSONPath(data any, path string) (any, bool, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, false, fmt.Errorf("invalid JSON path %s", path)
+	}
+
+	current := data
+	rest := path[1:]
+	for rest != "" {
+		var key string
+		index := -1
+		switch {
+		case rest[0] == '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key, rest = rest[:end], rest[end:]
+		case strings.HasPrefix(rest, "['") || strings.HasPrefix(rest, `["`):
+			quote := rest[1]
+			end := strings.IndexByte(rest[2:], quote)
+			if end < 0 || len(rest) < end+4 || rest[end+3] != ']' {
+				return nil, false, fmt.Errorf("invalid JSON path %s", path)
+			}
+			key, rest = rest[2:end+2], rest[end+4:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, false, fmt.Errorf("invalid JSON path %s", path)
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid JSON path %s", path)
+			}
+			index, rest = i, rest[end+1:]
+		default:
+			return nil, false, fmt.Errorf("invalid JSON path %s", path)
+		}
+
+		if index >= 0 {
+			arr, ok := current.([]any)
+			if !ok || index >= len(arr) {
+				return nil, false, nil
+			}
+			current = arr[index]
+			continue
+		}
+
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return nil, false, nil
+		}
+		current, ok = obj[key]
+		if !ok {
+			return nil, false, nil
+		}
+	}
+
+	return current, true, nil
+}
@@ -0,0 +1,351 @@
+package gql
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var maxAgeRegex = regexp.MustCompile(`max-age=(\d+)`)
+
+// jwkMinRefreshInterval limits forced refreshes when tokens with unknown key ids are received
+const jwkMinRefreshInterval = 10 * time.Second
+
+// jwtKey represents a verification key with its optional key id and algorithm
+type jwtKey struct {
+	kid string
+	alg string
+	key any
+}
+
+// jwk represents a JSON web key
+type jwk struct {
+	Kty string   `json:"kty"`
+	Kid string   `json:"kid"`
+	Alg string   `json:"alg"`
+	Use string   `json:"use"`
+	N   string   `json:"n"`
+	E   string   `json:"e"`
+	Crv string   `json:"crv"`
+	X   string   `json:"x"`
+	Y   string   `json:"y"`
+	K   string   `json:"k"`
+	X5c []string `json:"x5c"`
+}
+
+// jwkSet represents a JSON web key set
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// jwkSource loads and caches keys from a JWKS URL or file
+type jwkSource struct {
+	url             string
+	file            string
+	httpClient      *http.Client
+	refreshInterval time.Duration
+	now             func() time.Time
+
+	mu        sync.RWMutex
+	keys      []jwtKey
+	expiresAt time.Time
+	// refreshedAt the time of the last load attempt, including failed ones
+	refreshedAt time.Time
+}
+
+func (js *jwkSource) getKeys(ctx context.Context, forceRefresh bool) ([]jwtKey, error) {
+	js.mu.RLock()
+	keys, ok := js.cachedKeys(forceRefresh)
+	js.mu.RUnlock()
+	if ok {
+		return keys, nil
+	}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	// concurrent calls may have refreshed the keys while waiting for the lock
+	if keys, ok := js.cachedKeys(forceRefresh); ok {
+		return keys, nil
+	}
+
+	js.refreshedAt = js.now()
+	keys, maxAge, err := js.load(ctx)
+	if err != nil {
+		if js.keys != nil {
+			return js.keys, nil
+		}
+		return nil, err
+	}
+
+	js.keys = keys
+	if maxAge <= 0 {
+		maxAge = js.refreshInterval
+	}
+	js.expiresAt = js.refreshedAt.Add(maxAge)
+
+	return keys, nil
+}
+
+// cachedKeys returns the cached keys if they don't need to be loaded.
+// Forced refreshes and refreshes after failed loads are limited to one per jwkMinRefreshInterval
+func (js *jwkSource) cachedKeys(forceRefresh bool) ([]jwtKey, bool) {
+	if js.keys == nil {
+		return nil, false
+	}
+	now := js.now()
+	if now.Sub(js.refreshedAt) < jwkMinRefreshInterval {
+		return js.keys, true
+	}
+	expired := js.expiresAt.IsZero() || !now.Before(js.expiresAt)
+	return js.keys, !expired && !forceRefresh
+}
+
+func (js *jwkSource) load(ctx context.Context) ([]jwtKey, time.Duration, error) {
+	if js.file != "" {
+		bs, err := os.ReadFile(js.file)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read the JWKS file: %w", err)
+		}
+		keys, err := parseJWKSet(bs)
+		// a file doesn't change frequently, cache it until the process restarts
+		return keys, 100 * 365 * 24 * time.Hour, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, js.url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := js.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch the JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to fetch the JWKS: %s", resp.Status)
+	}
+
+	bs, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	keys, err := parseJWKSet(bs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return keys, parseCacheMaxAge(resp.Header, js.now()), nil
+}
+
+// parseCacheMaxAge gets the cache duration from Cache-Control or Expires headers
+func parseCacheMaxAge(header http.Header, now time.Time) time.Duration {
+	if matches := maxAgeRegex.FindStringSubmatch(header.Get("Cache-Control")); len(matches) > 1 {
+		seconds, err := strconv.Atoi(matches[1])
+		if err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	if expires := header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err == nil && t.After(now) {
+			return t.Sub(now)
+		}
+	}
+	return 0
+}
+
+func parseJWKSet(bs []byte) ([]jwtKey, error) {
+	var set jwkSet
+	if err := json.Unmarshal(bs, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make([]jwtKey, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWK %s: %w", k.Kid, err)
+		}
+		keys = append(keys, jwtKey{kid: k.Kid, alg: k.Alg, key: key})
+	}
+
+	return keys, nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64BigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64BigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBase64BigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64BigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+func decodeBase64BigInt(value string) (*big.Int, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bs), nil
+}
+
+// parseJWTKey parses the static key of the JWT config. HMAC algorithms use the raw key,
+// the others expect a PEM encoded public key or certificate
+func parseJWTKey(alg string, key string) (any, error) {
+	if len(alg) > 2 && alg[:2] == "HS" {
+		return []byte(key), nil
+	}
+
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return nil, errors.New("the key must be PEM encoded")
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+}
+
+// verifyJWTSignature verifies the signature of the signing input with the algorithm and key
+func verifyJWTSignature(alg string, key any, signingInput []byte, signature []byte) error {
+	switch alg {
+	case "HS256", "HS384", "HS512":
+		secret, ok := key.([]byte)
+		if !ok {
+			return errJWTKeyMismatch(alg)
+		}
+		mac := hmac.New(jwtHash(alg).New, secret)
+		mac.Write(signingInput)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errJWTKeyMismatch(alg)
+		}
+		hash := jwtHash(alg)
+		hasher := hash.New()
+		hasher.Write(signingInput)
+		if alg[0] == 'P' {
+			return rsa.VerifyPSS(publicKey, hash, hasher.Sum(nil), signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.VerifyPKCS1v15(publicKey, hash, hasher.Sum(nil), signature)
+	case "ES256", "ES384", "ES512":
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errJWTKeyMismatch(alg)
+		}
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature")
+		}
+		hasher := jwtHash(alg).New()
+		hasher.Write(signingInput)
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(publicKey, hasher.Sum(nil), r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case "EdDSA", "Ed25519":
+		publicKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return errJWTKeyMismatch(alg)
+		}
+		if !ed25519.Verify(publicKey, signingInput, signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported algorithm %s", alg)
+	}
+}
+
+func jwtHash(alg string) crypto.Hash {
+	switch alg[len(alg)-3:] {
+	case "384":
+		return crypto.SHA384
+	case "512":
+		return crypto.SHA512
+	default:
+		return crypto.SHA256
+	}
+}
+
+func errJWTKeyMismatch(alg string) error {
+	return fmt.Errorf("the key type doesn't match the algorithm %s", alg)
+}
@@ -0,0 +1,268 @@
+package gql
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func encodeTestJWT(t *testing.T, header map[string]any, claims map[string]any, sign func(input []byte) []byte) string {
+	t.Helper()
+	h, err := json.Marshal(header)
+	assert.NilError(t, err)
+	c, err := json.Marshal(claims)
+	assert.NilError(t, err)
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(input)))
+}
+
+func TestJWTParser_JWKURL(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=300")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]any{{
+				"kty": "RSA",
+				"kid": "key-1",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	config, err := ParseJWTConfig(`{"jwk_url": "` + server.URL + `", "audience": ["app"], "issuer": "test"}`)
+	assert.NilError(t, err)
+	parser, err := NewJWTParser(*config)
+	assert.NilError(t, err)
+
+	sign := func(input []byte) []byte {
+		hashed := sha256.Sum256(input)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hashed[:])
+		assert.NilError(t, err)
+		return sig
+	}
+	claims := map[string]any{
+		"sub": "1",
+		"aud": "app",
+		"iss": "test",
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"https://hasura.io/jwt/claims": map[string]any{
+			"x-hasura-allowed-roles": []string{"user", "editor"},
+			"x-hasura-default-role":  "user",
+			"x-hasura-user-id":       "1",
+			"x-hasura-org-id":        10,
+		},
+	}
+	token := encodeTestJWT(t, map[string]any{"alg": "RS256", "kid": "key-1"}, claims, sign)
+
+	sv, err := parser.Parse(context.Background(), token, "")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, SessionVariables{
+		XHasuraRole:         "user",
+		XHasuraAllowedRoles: "{user,editor}",
+		XHasuraDefaultRole:  "user",
+		XHasuraUserID:       "1",
+		"x-hasura-org-id":   "10",
+	}, sv)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Hasura-Role", "editor")
+	sv, err = parser.Authenticate(context.Background(), req)
+	assert.NilError(t, err)
+	assert.Equal(t, "editor", sv.GetRole())
+
+	_, err = parser.Parse(context.Background(), token, "admin")
+	assert.ErrorIs(t, err, ErrJWTRoleDenied)
+
+	claims["aud"] = "other"
+	_, err = parser.Parse(context.Background(), encodeTestJWT(t, map[string]any{"alg": "RS256", "kid": "key-1"}, claims, sign), "")
+	assert.ErrorIs(t, err, ErrJWTInvalidClaims)
+
+	claims["aud"] = "app"
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	_, err = parser.Parse(context.Background(), encodeTestJWT(t, map[string]any{"alg": "RS256", "kid": "key-1"}, claims, sign), "")
+	assert.ErrorIs(t, err, ErrJWTExpired)
+
+	_, err = parser.Parse(context.Background(), token[:len(token)-4]+"abcd", "")
+	assert.ErrorIs(t, err, ErrJWTInvalid)
+}
+
+func TestJWTParser_JWKFile(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+	jwks, err := json.Marshal(map[string]any{
+		"keys": []map[string]any{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+		}},
+	})
+	assert.NilError(t, err)
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NilError(t, os.WriteFile(file, jwks, 0o600))
+
+	config, err := ParseJWTConfig(`{"jwk_file": "` + file + `"}`)
+	assert.NilError(t, err)
+	parser, err := NewJWTParser(*config)
+	assert.NilError(t, err)
+
+	sign := func(input []byte) []byte {
+		hashed := sha256.Sum256(input)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hashed[:])
+		assert.NilError(t, err)
+		return sig
+	}
+	token := encodeTestJWT(t, map[string]any{"alg": "RS256", "kid": "key-1"}, map[string]any{
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"https://hasura.io/jwt/claims": map[string]any{
+			"x-hasura-allowed-roles": []string{"user"},
+			"x-hasura-default-role":  "user",
+			// large integers keep their precision
+			"x-hasura-user-id": int64(9007199254740993),
+		},
+	}, sign)
+
+	sv, err := parser.Parse(context.Background(), token, "")
+	assert.NilError(t, err)
+	assert.Equal(t, "9007199254740993", sv.Get(XHasuraUserID))
+
+	// the file is cached, a missing file doesn't fail cached keys
+	assert.NilError(t, os.Remove(file))
+	_, err = parser.Parse(context.Background(), token, "")
+	assert.NilError(t, err)
+
+	config, err = ParseJWTConfig(`{"jwk_file": "` + file + `"}`)
+	assert.NilError(t, err)
+	parser, err = NewJWTParser(*config)
+	assert.NilError(t, err)
+	_, err = parser.Parse(context.Background(), token, "")
+	assert.ErrorContains(t, err, "failed to read the JWKS file")
+}
+
+func TestJWKSource_RefreshLimit(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"keys": [{"kty": "oct", "kid": "key-1", "k": "c2VjcmV0"}]}`))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	source := &jwkSource{
+		url:             server.URL,
+		httpClient:      http.DefaultClient,
+		refreshInterval: time.Hour,
+		now:             func() time.Time { return now },
+	}
+	keys, err := source.getKeys(context.Background(), false)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(keys))
+
+	// concurrent refreshes of unknown key ids are limited
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := source.getKeys(context.Background(), true)
+			assert.Check(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), requests.Load())
+
+	now = now.Add(jwkMinRefreshInterval)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := source.getKeys(context.Background(), true)
+			assert.Check(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestJWTParser_ClaimsMap(t *testing.T) {
+	secret := "a-string-secret-at-least-256-bits-long"
+	sign := func(input []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(input)
+		return mac.Sum(nil)
+	}
+
+	config, err := ParseJWTConfig(`{
+		"type": "HS256",
+		"key": "` + secret + `",
+		"claims_map": {
+			"x-hasura-allowed-roles": {"path": "$.hasura['all-roles']"},
+			"x-hasura-default-role": "user",
+			"x-hasura-user-id": {"path": "$.user.id"},
+			"x-hasura-org-id": {"path": "$.user.org_id", "default": "0"}
+		}
+	}`)
+	assert.NilError(t, err)
+	parser, err := NewJWTParser(*config)
+	assert.NilError(t, err)
+
+	token := encodeTestJWT(t, map[string]any{"alg": "HS256"}, map[string]any{
+		"hasura": map[string]any{"all-roles": []string{"user"}},
+		"user":   map[string]any{"id": "abc"},
+	}, sign)
+	sv, err := parser.Parse(context.Background(), token, "")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, SessionVariables{
+		XHasuraRole:         "user",
+		XHasuraAllowedRoles: "{user}",
+		XHasuraDefaultRole:  "user",
+		XHasuraUserID:       "abc",
+		"x-hasura-org-id":   "0",
+	}, sv)
+
+	stringified, err := NewJWTParser(JWTConfig{
+		Type:                "HS256",
+		Key:                 secret,
+		ClaimsNamespacePath: "$.hasura.claims",
+		ClaimsFormat:        JWTClaimsFormatStringifiedJSON,
+	})
+	assert.NilError(t, err)
+	token = encodeTestJWT(t, map[string]any{"alg": "HS256"}, map[string]any{
+		"hasura": map[string]any{
+			"claims": `{"x-hasura-allowed-roles": ["user"], "x-hasura-default-role": "user", "x-hasura-user-id": "2"}`,
+		},
+	}, sign)
+	sv, err = stringified.Parse(context.Background(), token, "")
+	assert.NilError(t, err)
+	assert.Equal(t, "2", sv.Get(XHasuraUserID))
+
+	token = encodeTestJWT(t, map[string]any{"alg": "HS256"}, map[string]any{
+		"hasura": map[string]any{"claims": `{"x-hasura-user-id": "2"}`},
+	}, sign)
+	_, err = stringified.Parse(context.Background(), token, "")
+	assert.ErrorIs(t, err, ErrJWTMissingRole)
+}
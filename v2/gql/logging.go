@@ -0,0 +1,242 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+// RedactedValue the replacement of redacted values in logs
+const RedactedValue = "[REDACTED]"
+
+// fields that are always redacted in logs
+var alwaysRedactedFields = []string{XHasuraAdminSecret, authorizationHeader}
+
+type logOptions struct {
+	logger        *slog.Logger
+	logVariables  bool
+	logResponses  bool
+	slowThreshold time.Duration
+	redactPaths   []string
+	redactFields  []string
+}
+
+// WithLogger sets the structured logger of operations. Successful operations are logged at the debug level,
+// slow operations at the warn level and failures at the error level
+func WithLogger(logger *slog.Logger) Option {
+	return func(opts *options) {
+		opts.logging.logger = logger
+	}
+}
+
+// WithLogVariables includes redacted variables and session variables in operation logs
+func WithLogVariables() Option {
+	return func(opts *options) {
+		opts.logging.logVariables = true
+	}
+}
+
+// WithLogResponses includes redacted responses in operation logs
+func WithLogResponses() Option {
+	return func(opts *options) {
+		opts.logging.logResponses = true
+	}
+}
+
+// WithSlowThreshold logs operations that are slower than the threshold at the warn level
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(opts *options) {
+		opts.logging.slowThreshold = threshold
+	}
+}
+
+// WithLogRedactPaths redacts variables at dot-separated paths, e.g. input.user.email.
+// The * segment matches any field. Array items are matched by the path of the array
+func WithLogRedactPaths(paths ...string) Option {
+	return func(opts *options) {
+		opts.logging.redactPaths = append(opts.logging.redactPaths, paths...)
+	}
+}
+
+// WithLogRedactFields redacts fields of variables and responses by name in any depth, case-insensitively.
+// The admin secret and the authorization header are always redacted
+func WithLogRedactFields(names ...string) Option {
+	return func(opts *options) {
+		opts.logging.redactFields = append(opts.logging.redactFields, names...)
+	}
+}
+
+// operationLogger logs operations of the client with redaction rules
+type operationLogger struct {
+	logger        *slog.Logger
+	logVariables  bool
+	logResponses  bool
+	slowThreshold time.Duration
+	redactPaths   [][]string
+	redactFields  map[string]bool
+}
+
+func newOperationLogger(opts logOptions) *operationLogger {
+	if opts.logger == nil {
+		return nil
+	}
+
+	ol := &operationLogger{
+		logger:        opts.logger,
+		logVariables:  opts.logVariables,
+		logResponses:  opts.logResponses,
+		slowThreshold: opts.slowThreshold,
+		redactFields:  map[string]bool{},
+	}
+	for _, name := range append(opts.redactFields, alwaysRedactedFields...) {
+		ol.redactFields[strings.ToLower(name)] = true
+	}
+	for _, path := range opts.redactPaths {
+		path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+		if path != "" {
+			ol.redactPaths = append(ol.redactPaths, strings.Split(path, "."))
+		}
+	}
+
+	return ol
+}
+
+func (ol *operationLogger) log(ctx context.Context, c *HasuraClient, op operation, operationName string, duration time.Duration, response []byte, err error) {
+	if ol == nil {
+		return
+	}
+
+	level := slog.LevelDebug
+	if err != nil {
+		level = slog.LevelError
+	} else if ol.slowThreshold > 0 && duration >= ol.slowThreshold {
+		level = slog.LevelWarn
+	}
+	if !ol.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("operation", op.method),
+		slog.String("operation_type", op.operationType),
+		slog.Duration("duration", duration),
+	}
+	if operationName != "" {
+		attrs = append(attrs, slog.String("operation_name", operationName))
+	}
+	role := c.sessionVariables.GetRole()
+	if state := getRequestState(ctx); state != nil {
+		role = state.role
+		attrs = append(attrs, slog.String("request_id", state.requestID))
+		if state.statusCode > 0 {
+			attrs = append(attrs, slog.Int("status", state.statusCode))
+		}
+	}
+	if role == "" && c.isAdmin() {
+		role = RoleAdmin
+	}
+	if role != "" {
+		attrs = append(attrs, slog.String("role", role))
+	}
+	if ol.logVariables {
+		attrs = append(attrs,
+			slog.Any("variables", ol.redact(op.variables)),
+			slog.Any("session_variables", ol.redact(getHeadersFromContext(ctx))),
+		)
+	}
+	if ol.logResponses && err == nil {
+		if op.result != nil {
+			attrs = append(attrs, slog.Any("response", ol.redact(op.result)))
+		} else if len(response) > 0 {
+			attrs = append(attrs, slog.Any("response", ol.redact(json.RawMessage(response))))
+		}
+	}
+
+	message := "hasura operation"
+	if err != nil {
+		message = "hasura operation failure"
+		attrs = append(attrs, slog.String("error", err.Error()))
+		if errorCodes := getErrorCodes(err); len(errorCodes) > 0 {
+			attrs = append(attrs, slog.Any("error_codes", errorCodes))
+		}
+	} else if level == slog.LevelWarn {
+		message = "slow hasura operation"
+	}
+
+	ol.logger.LogAttrs(ctx, level, message, attrs...)
+}
+
+// redact converts the value to generic JSON and replaces values of redacted paths and fields
+func (ol *operationLogger) redact(value any) any {
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("<%s>", err)
+	}
+	var generic any
+	if err := json.Unmarshal(bs, &generic); err != nil {
+		return fmt.Sprintf("<%s>", err)
+	}
+	return ol.redactValue(generic, nil)
+}
+
+func (ol *operationLogger) redactValue(value any, path []string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			itemPath := append(path[:len(path):len(path)], key)
+			if ol.redactFields[strings.ToLower(key)] || ol.matchPath(itemPath) {
+				v[key] = RedactedValue
+				continue
+			}
+			v[key] = ol.redactValue(item, itemPath)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = ol.redactValue(item, path)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func (ol *operationLogger) matchPath(path []string) bool {
+	for _, redactPath := range ol.redactPaths {
+		if len(redactPath) != len(path) {
+			continue
+		}
+		matched := true
+		for i, segment := range redactPath {
+			if segment != "*" && segment != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// getErrorCodes gets the codes in extensions of GraphQL errors
+func getErrorCodes(err error) []string {
+	var gqlErrors graphql.Errors
+	if !errors.As(err, &gqlErrors) {
+		return nil
+	}
+	var results []string
+	for _, e := range gqlErrors {
+		if code, ok := e.Extensions["code"].(string); ok && code != "" {
+			results = append(results, code)
+		}
+	}
+	return results
+}
@@ -0,0 +1,83 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestHasuraClient_Logger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			_, _ = w.Write([]byte(`{"errors": [{"message": "field not found", "extensions": {"code": "validation-failed"}}]}`))
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"data": {"users": [{"id": 1, "email": "foo@example.com"}]}}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	options := []Option{
+		WithLogger(logger),
+		WithLogVariables(),
+		WithLogResponses(),
+		WithLogRedactPaths("input.password", "$.input.tokens.*"),
+		WithLogRedactFields("Email"),
+		WithSlowThreshold(time.Millisecond),
+	}
+	client, err := NewAdminClient(server.URL, "secret", options...).AsRole("user", "1")
+	assert.NilError(t, err)
+
+	_, err = client.ExecRaw(context.Background(), "query GetUsers { users { id email } }", map[string]any{
+		"input": map[string]any{
+			"name":     "foo",
+			"password": "bar",
+			"tokens":   map[string]any{"a": "1"},
+		},
+	}, CallHeader("Authorization", "Bearer token"))
+	assert.NilError(t, err)
+
+	var record map[string]any
+	assert.NilError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "slow hasura operation", record["msg"])
+	assert.Equal(t, "ExecRaw", record["operation"])
+	assert.Equal(t, "query", record["operation_type"])
+	assert.Equal(t, "user", record["role"])
+	assert.Equal(t, float64(200), record["status"])
+	assert.DeepEqual(t, map[string]any{
+		"input": map[string]any{
+			"name":     "foo",
+			"password": RedactedValue,
+			"tokens":   map[string]any{"a": RedactedValue},
+		},
+	}, record["variables"])
+	sessionVariables := record["session_variables"].(map[string]any)
+	assert.Equal(t, RedactedValue, sessionVariables[XHasuraAdminSecret])
+	assert.Equal(t, RedactedValue, sessionVariables[authorizationHeader])
+	assert.Equal(t, "user", sessionVariables[XHasuraRole])
+	assert.DeepEqual(t, map[string]any{
+		"users": []any{map[string]any{"id": float64(1), "email": RedactedValue}},
+	}, record["response"])
+
+	buf.Reset()
+	errorClient := NewAdminClient(server.URL+"/error", "secret", WithLogger(logger))
+	_, err = errorClient.ExecRaw(context.Background(), "mutation { foo }", nil)
+	assert.ErrorContains(t, err, "field not found")
+	record = map[string]any{}
+	assert.NilError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, "mutation", record["operation_type"])
+	assert.Equal(t, RoleAdmin, record["role"])
+	assert.DeepEqual(t, []any{"validation-failed"}, record["error_codes"])
+	assert.Assert(t, record["variables"] == nil)
+}
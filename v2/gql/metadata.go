@@ -0,0 +1,111 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hgiasac/hasura-utils/v2/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var errMetadataAdminRequired = errors.New("metadata API requires the admin secret")
+
+// MetadataRequest represents the request body of Hasura metadata API
+type MetadataRequest struct {
+	Type    string `json:"type"`
+	Version int    `json:"version,omitempty"`
+	Args    any    `json:"args"`
+}
+
+// metadataErrorResponse represents the error body of Hasura metadata API
+type metadataErrorResponse struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// MetadataURL returns the metadata API endpoint that is derived from the GraphQL endpoint
+func (c *HasuraClient) MetadataURL() string {
+	return strings.TrimSuffix(strings.TrimSuffix(c.endpoint, "/"), "/v1/graphql") + "/v1/metadata"
+}
+
+// Metadata calls the Hasura metadata API with the admin secret and decodes the response into the result
+func (c *HasuraClient) Metadata(ctx context.Context, request MetadataRequest, result any) error {
+	ctx, span := c.startSpan(ctx, "Metadata", nil)
+	defer span.End()
+	span.SetAttributes(attribute.String("metadata_type", request.Type))
+
+	err := c.checkReadOnlyMetadata(request)
+	if err == nil {
+		err = c.metadata(ctx, request, result)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "metadata failure")
+		span.RecordError(err)
+	}
+	return err
+}
+
+func (c *HasuraClient) metadata(ctx context.Context, request MetadataRequest, result any) error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.adminSecret == "" {
+		return errMetadataAdminRequired
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.MetadataURL(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(XHasuraAdminSecret, c.adminSecret)
+	if c.clientName != "" {
+		req.Header.Set(HasuraClientName, c.clientName)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp metadataErrorResponse
+		if err := json.Unmarshal(respBody, &errResp); err != nil || errResp.Error == "" {
+			return types.ErrUnknown(fmt.Errorf("%s: %s", resp.Status, string(respBody)), map[string]any{
+				"status": resp.StatusCode,
+			})
+		}
+		return types.NewError(errResp.Code, errResp.Error, map[string]any{
+			"path":   errResp.Path,
+			"status": resp.StatusCode,
+		})
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return types.ErrDecodeJSON(err, nil)
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+// CollectedQuery represents a GraphQL document that the client sends, with the stable hash of the normalized document
+type CollectedQuery struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	Hash  string `json:"-"`
+}
+
+// QueryCollector collects GraphQL documents of registered Go query and mutation types for allow-lists
+type QueryCollector struct {
+	queries map[string]CollectedQuery
+}
+
+// NewQueryCollector creates an empty query collector
+func NewQueryCollector() *QueryCollector {
+	return &QueryCollector{
+		queries: map[string]CollectedQuery{},
+	}
+}
+
+// AddQuery registers the query document that Query and QueryRaw build from the struct, variables and options.
+// Variables need values of the same Go types as the calls only, e.g. zero values
+func (qc *QueryCollector) AddQuery(name string, q any, variables map[string]any, options ...graphql.Option) error {
+	return qc.addOperation(name, operation{
+		operationType: operationQuery,
+		value:         q,
+		variables:     variables,
+	}, options)
+}
+
+// AddMutation registers the mutation document that Mutate and MutateRaw build from the struct, variables and options
+func (qc *QueryCollector) AddMutation(name string, m any, variables map[string]any, options ...graphql.Option) error {
+	return qc.addOperation(name, operation{
+		operationType: operationMutation,
+		value:         m,
+		variables:     variables,
+	}, options)
+}
+
+// AddRaw registers the raw query document of Exec and ExecRaw
+func (qc *QueryCollector) AddRaw(name string, query string) error {
+	return qc.add(name, query)
+}
+
+func (qc *QueryCollector) addOperation(name string, op operation, options []graphql.Option) error {
+	query, err := op.document(stripCallOptions(options))
+	if err != nil {
+		return fmt.Errorf("failed to build the document of query %s: %w", name, err)
+	}
+	return qc.add(name, query)
+}
+
+func (qc *QueryCollector) add(name string, query string) error {
+	hash, err := QueryHash(query)
+	if err != nil {
+		return fmt.Errorf("invalid document of query %s: %w", name, err)
+	}
+	if existing, ok := qc.queries[name]; ok && existing.Hash != hash {
+		return fmt.Errorf("query %s is registered with a different document", name)
+	}
+	qc.queries[name] = CollectedQuery{
+		Name:  name,
+		Query: query,
+		Hash:  hash,
+	}
+	return nil
+}
+
+// Queries returns collected queries sorted by name
+func (qc *QueryCollector) Queries() []CollectedQuery {
+	results := make([]CollectedQuery, 0, len(qc.queries))
+	for _, q := range qc.queries {
+		results = append(results, q)
+	}
+	slices.SortFunc(results, func(a, b CollectedQuery) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return results
+}
+
+// stripCallOptions removes per-call options that aren't rendered in documents
+func stripCallOptions(options []graphql.Option) []graphql.Option {
+	results := make([]graphql.Option, 0, len(options))
+	for _, opt := range options {
+		if _, ok := opt.(callOption); !ok {
+			results = append(results, opt)
+		}
+	}
+	return results
+}
+
+// QueryCollection represents a query collection in Hasura metadata
+type QueryCollection struct {
+	Name       string `json:"name"`
+	Comment    string `json:"comment,omitempty"`
+	Definition struct {
+		Queries []CollectedQuery `json:"queries"`
+	} `json:"definition"`
+}
+
+// AllowlistEntry represents an allow-list entry in Hasura metadata
+type AllowlistEntry struct {
+	Collection string         `json:"collection"`
+	Scope      map[string]any `json:"scope,omitempty"`
+}
+
+// exportedMetadata represents the query collections and the allow-list of export_metadata
+type exportedMetadata struct {
+	QueryCollections []QueryCollection `json:"query_collections"`
+	Allowlist        []AllowlistEntry  `json:"allowlist"`
+}
+
+// SyncQueryCollectionInput represents the arguments of SyncQueryCollection
+type SyncQueryCollectionInput struct {
+	// Collection the name of the query collection
+	Collection string
+	Queries    []CollectedQuery
+	// SkipAllowlist doesn't add the collection to the allow-list
+	SkipAllowlist bool
+	// DryRun reports the changes without modifying the metadata
+	DryRun bool
+}
+
+// QueryCollectionSyncReport represents the result of SyncQueryCollection
+type QueryCollectionSyncReport struct {
+	DryRun            bool
+	CollectionCreated bool
+	AddedToAllowlist  bool
+	// Added names of queries that are added to the collection
+	Added []string
+	// Updated names of queries whose documents are replaced
+	Updated []string
+	// Unchanged names of queries that are in sync
+	Unchanged []string
+	// MissingFromAllowlist names of queries that weren't in any allow-listed collection before the sync
+	MissingFromAllowlist []string
+}
+
+// ExportQueryCollections exports query collections and the allow-list from Hasura metadata
+func (c *HasuraClient) ExportQueryCollections(ctx context.Context) ([]QueryCollection, []AllowlistEntry, error) {
+	var metadata exportedMetadata
+	if err := c.Metadata(ctx, MetadataRequest{
+		Type: "export_metadata",
+		Args: map[string]any{},
+	}, &metadata); err != nil {
+		return nil, nil, err
+	}
+	return metadata.QueryCollections, metadata.Allowlist, nil
+}
+
+// SyncQueryCollection syncs collected queries to the Hasura query collection and adds the collection to the allow-list.
+// Queries are compared by the hash of normalized documents, the Hash field of the input queries is ignored.
+// Queries of the collection that aren't collected are kept
+func (c *HasuraClient) SyncQueryCollection(ctx context.Context, input SyncQueryCollectionInput) (*QueryCollectionSyncReport, error) {
+	if input.Collection == "" {
+		return nil, fmt.Errorf("collection name is required")
+	}
+	hashes := make(map[string]string, len(input.Queries))
+	for _, q := range input.Queries {
+		hash, err := QueryHash(q.Query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid document of query %s: %w", q.Name, err)
+		}
+		hashes[q.Name] = hash
+	}
+	collections, allowlist, err := c.ExportQueryCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &QueryCollectionSyncReport{
+		DryRun:               input.DryRun,
+		MissingFromAllowlist: missingFromAllowlist(input.Queries, collections, allowlist),
+	}
+
+	var existing map[string]string
+	for _, collection := range collections {
+		if collection.Name == input.Collection {
+			existing = hashCollectionQueries(collection.Definition.Queries)
+			break
+		}
+	}
+
+	var requests []MetadataRequest
+	if existing == nil {
+		report.CollectionCreated = true
+		definition := QueryCollection{Name: input.Collection}
+		definition.Definition.Queries = input.Queries
+		requests = append(requests, MetadataRequest{
+			Type: "create_query_collection",
+			Args: definition,
+		})
+		for _, q := range input.Queries {
+			report.Added = append(report.Added, q.Name)
+		}
+	} else {
+		for _, q := range input.Queries {
+			hash, ok := existing[q.Name]
+			switch {
+			case !ok:
+				report.Added = append(report.Added, q.Name)
+			case hash != hashes[q.Name]:
+				report.Updated = append(report.Updated, q.Name)
+				requests = append(requests, MetadataRequest{
+					Type: "drop_query_from_collection",
+					Args: map[string]any{
+						"collection_name": input.Collection,
+						"query_name":      q.Name,
+					},
+				})
+			default:
+				report.Unchanged = append(report.Unchanged, q.Name)
+				continue
+			}
+			requests = append(requests, MetadataRequest{
+				Type: "add_query_to_collection",
+				Args: map[string]any{
+					"collection_name": input.Collection,
+					"query_name":      q.Name,
+					"query":           q.Query,
+				},
+			})
+		}
+	}
+
+	if !input.SkipAllowlist && !slices.ContainsFunc(allowlist, func(entry AllowlistEntry) bool {
+		return entry.Collection == input.Collection
+	}) {
+		report.AddedToAllowlist = true
+		requests = append(requests, MetadataRequest{
+			Type: "add_collection_to_allowlist",
+			Args: map[string]any{
+				"collection": input.Collection,
+			},
+		})
+	}
+
+	if input.DryRun || len(requests) == 0 {
+		return report, nil
+	}
+
+	// the bulk request applies all changes atomically
+	if err := c.Metadata(ctx, MetadataRequest{
+		Type: "bulk",
+		Args: requests,
+	}, nil); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// CheckAllowlist reports names of collected queries that aren't in any allow-listed collection.
+// Queries are compared by the hash of normalized documents, the Hash field is ignored
+func (c *HasuraClient) CheckAllowlist(ctx context.Context, queries []CollectedQuery) ([]string, error) {
+	collections, allowlist, err := c.ExportQueryCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return missingFromAllowlist(queries, collections, allowlist), nil
+}
+
+func missingFromAllowlist(queries []CollectedQuery, collections []QueryCollection, allowlist []AllowlistEntry) []string {
+	allowed := map[string]bool{}
+	for _, collection := range collections {
+		if !slices.ContainsFunc(allowlist, func(entry AllowlistEntry) bool {
+			return entry.Collection == collection.Name
+		}) {
+			continue
+		}
+		for _, hash := range hashCollectionQueries(collection.Definition.Queries) {
+			allowed[hash] = true
+		}
+	}
+
+	hashes := hashCollectionQueries(queries)
+	var missing []string
+	for _, q := range queries {
+		if hash := hashes[q.Name]; hash == "" || !allowed[hash] {
+			missing = append(missing, q.Name)
+		}
+	}
+	return missing
+}
+
+// hashCollectionQueries computes hashes of queries in the collection by name.
+// Invalid documents get an empty hash so they never match
+func hashCollectionQueries(queries []CollectedQuery) map[string]string {
+	results := make(map[string]string, len(queries))
+	for _, q := range queries {
+		hash, _ := QueryHash(q.Query)
+		results[q.Name] = hash
+	}
+	return results
+}
@@ -0,0 +1,133 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+	"gotest.tools/v3/assert"
+)
+
+func TestHasuraClient_SyncQueryCollection(t *testing.T) {
+	var bulk []MetadataRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Type string          `json:"type"`
+			Args json.RawMessage `json:"args"`
+		}
+		assert.Check(t, json.NewDecoder(r.Body).Decode(&body))
+		switch body.Type {
+		case "export_metadata":
+			_, _ = w.Write([]byte(`{
+				"version": 3,
+				"query_collections": [
+					{"name": "app", "definition": {"queries": [
+						{"name": "GetUsers", "query": "query GetUsers {\n  users {\n    id\n  }\n}"},
+						{"name": "DeleteUser", "query": "mutation DeleteUser { delete_users(where: {}) { affected_rows } }"}
+					]}},
+					{"name": "other", "definition": {"queries": [
+						{"name": "GetPosts", "query": "query GetPosts { posts { id } }"}
+					]}}
+				],
+				"allowlist": [{"collection": "other"}]
+			}`))
+		case "bulk":
+			assert.Check(t, json.Unmarshal(body.Args, &bulk))
+			_, _ = w.Write([]byte(`[{"message": "success"}]`))
+		}
+	}))
+	defer server.Close()
+
+	var getUsers struct {
+		Users []struct {
+			ID int `graphql:"id"`
+		} `graphql:"users"`
+	}
+	var deleteUser struct {
+		DeleteUsers struct {
+			AffectedRows int `graphql:"affected_rows"`
+		} `graphql:"delete_users(where: {id: {_eq: $id}})"`
+	}
+	collector := NewQueryCollector()
+	assert.NilError(t, collector.AddQuery("GetUsers", &getUsers, nil, graphql.OperationName("GetUsers"), CallLocalCache(0, "")))
+	assert.NilError(t, collector.AddMutation("DeleteUser", &deleteUser, map[string]any{"id": 0}, graphql.OperationName("DeleteUser")))
+	assert.NilError(t, collector.AddRaw("GetPosts", "query GetPosts {\n  posts { id }\n}"))
+	assert.ErrorContains(t, collector.AddRaw("GetPosts", "query GetPosts { posts { title } }"), "different document")
+
+	queries := collector.Queries()
+	assert.DeepEqual(t, []string{"DeleteUser", "GetPosts", "GetUsers"}, []string{queries[0].Name, queries[1].Name, queries[2].Name})
+	assert.Equal(t, "mutation DeleteUser($id:Int!){delete_users(where: {id: {_eq: $id}}){affected_rows}}", queries[0].Query)
+
+	client := NewAdminClient(server.URL+"/v1/graphql", "secret")
+	missing, err := client.CheckAllowlist(context.Background(), queries)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"DeleteUser", "GetUsers"}, missing)
+
+	report, err := client.SyncQueryCollection(context.Background(), SyncQueryCollectionInput{
+		Collection: "app",
+		Queries:    queries,
+		DryRun:     true,
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, bulk == nil)
+	assert.DeepEqual(t, &QueryCollectionSyncReport{
+		DryRun:               true,
+		AddedToAllowlist:     true,
+		Added:                []string{"GetPosts"},
+		Updated:              []string{"DeleteUser"},
+		Unchanged:            []string{"GetUsers"},
+		MissingFromAllowlist: []string{"DeleteUser", "GetUsers"},
+	}, report)
+
+	// queries loaded from JSON have no hash, so hashes are computed from the documents
+	bs, err := json.Marshal(queries)
+	assert.NilError(t, err)
+	var loadedQueries []CollectedQuery
+	assert.NilError(t, json.Unmarshal(bs, &loadedQueries))
+	assert.Equal(t, "", loadedQueries[0].Hash)
+	missing, err = client.CheckAllowlist(context.Background(), loadedQueries)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"DeleteUser", "GetUsers"}, missing)
+	loadedReport, err := client.SyncQueryCollection(context.Background(), SyncQueryCollectionInput{
+		Collection: "app",
+		Queries:    loadedQueries,
+		DryRun:     true,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, report, loadedReport)
+
+	_, err = client.SyncQueryCollection(context.Background(), SyncQueryCollectionInput{
+		Collection: "app",
+		Queries:    []CollectedQuery{{Name: "Invalid", Query: `query { users(where: "x) { id } }`}},
+		DryRun:     true,
+	})
+	assert.ErrorContains(t, err, "invalid document of query Invalid")
+
+	_, err = client.SyncQueryCollection(context.Background(), SyncQueryCollectionInput{
+		Collection: "app",
+		Queries:    queries,
+	})
+	assert.NilError(t, err)
+	types := make([]string, len(bulk))
+	for i, req := range bulk {
+		types[i] = req.Type
+	}
+	assert.DeepEqual(t, []string{
+		"drop_query_from_collection",
+		"add_query_to_collection",
+		"add_query_to_collection",
+		"add_collection_to_allowlist",
+	}, types)
+
+	report, err = client.SyncQueryCollection(context.Background(), SyncQueryCollectionInput{
+		Collection: "new",
+		Queries:    queries,
+		DryRun:     true,
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, report.CollectionCreated)
+	assert.DeepEqual(t, []string{"DeleteUser", "GetPosts", "GetUsers"}, report.Added)
+}
@@ -0,0 +1,106 @@
+package gql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hgiasac/hasura-utils/v2/types"
+)
+
+// ErrReadOnly is the reason of permission denied errors of mutations and metadata writes in read-only mode
+var ErrReadOnly = errors.New("writes are not allowed in read-only mode")
+
+// metadata request types that read metadata or logs only, in addition to get_* and <source>_get_* types
+var readOnlyMetadataTypes = []string{"export_metadata", "list_source_kinds"}
+
+// bulk metadata request types whose arguments are metadata requests
+var bulkMetadataTypes = []string{"bulk", "bulk_keep_going", "bulk_atomic", "concurrent_bulk"}
+
+// WithReadOnly rejects mutations and metadata writes of the client and its derived clients
+func WithReadOnly() Option {
+	return func(opts *options) {
+		opts.readOnly = true
+	}
+}
+
+// ReadOnly returns a derived client that rejects Mutate and MutateRaw calls,
+// and Exec and ExecRaw calls whose documents contain mutation operations, with permission_denied errors.
+// Metadata requests are allowed only if they read metadata or logs, e.g. export_metadata and pg_get_event_logs,
+// so run_sql, scheduled event and cron trigger writes and event redelivery are rejected.
+// The read-only mode survives As, AsRole and other derivations, and can't be disabled
+func (c *HasuraClient) ReadOnly() *HasuraClient {
+	derived := *c
+	derived.readOnly = true
+	return &derived
+}
+
+// IsReadOnly checks if the client rejects mutations
+func (c *HasuraClient) IsReadOnly() bool {
+	return c.readOnly
+}
+
+// checkReadOnly rejects mutations of read-only clients. Raw documents are parsed,
+// and documents that can't be parsed are rejected because they can't be verified
+func (c *HasuraClient) checkReadOnly(op operation, query string) error {
+	if !c.readOnly {
+		return nil
+	}
+	extensions := map[string]any{
+		"operation": op.method,
+	}
+	if op.query == "" {
+		if op.operationType != operationMutation {
+			return nil
+		}
+		return types.ErrPermissionDenied(ErrReadOnly, extensions)
+	}
+
+	operationTypes, err := parseOperationTypes(query)
+	if err != nil {
+		return types.ErrPermissionDenied(fmt.Errorf("%w: failed to parse the document: %w", ErrReadOnly, err), extensions)
+	}
+	if slices.Contains(operationTypes, operationMutation) {
+		return types.ErrPermissionDenied(ErrReadOnly, extensions)
+	}
+	return nil
+}
+
+// checkReadOnlyMetadata rejects metadata requests that may write of read-only clients
+func (c *HasuraClient) checkReadOnlyMetadata(request MetadataRequest) error {
+	if !c.readOnly || isReadOnlyMetadataRequest(request) {
+		return nil
+	}
+	return types.ErrPermissionDenied(fmt.Errorf("%w: metadata request %s", ErrReadOnly, request.Type), map[string]any{
+		"operation":     "Metadata",
+		"metadata_type": request.Type,
+	})
+}
+
+// isReadOnlyMetadataRequest checks if the metadata request and nested requests of bulk requests read only
+func isReadOnlyMetadataRequest(request MetadataRequest) bool {
+	if slices.Contains(bulkMetadataTypes, request.Type) {
+		bs, err := json.Marshal(request.Args)
+		if err != nil {
+			return false
+		}
+		var requests []MetadataRequest
+		if err := json.Unmarshal(bs, &requests); err != nil {
+			return false
+		}
+		for _, req := range requests {
+			if !isReadOnlyMetadataRequest(req) {
+				return false
+			}
+		}
+		return true
+	}
+	if slices.Contains(readOnlyMetadataTypes, request.Type) || strings.HasPrefix(request.Type, "get_") {
+		return true
+	}
+	// source-specific reads, e.g. pg_get_event_logs
+	source, action, ok := strings.Cut(request.Type, "_")
+	return ok && source != "" && strings.HasPrefix(action, "get_")
+}
@@ -0,0 +1,89 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	rtypes "github.com/hgiasac/hasura-router/go/types"
+	"github.com/hgiasac/hasura-utils/v2/types"
+	"gotest.tools/v3/assert"
+)
+
+func TestHasuraClient_ReadOnly(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"data": {"users": []}}`))
+	}))
+	defer server.Close()
+
+	client := NewAdminClient(server.URL, "secret")
+	assert.Assert(t, !client.IsReadOnly())
+	readOnlyClient := client.ReadOnly()
+	assert.Assert(t, readOnlyClient.IsReadOnly())
+	userClient, err := readOnlyClient.AsRole("user", "1")
+	assert.NilError(t, err)
+	assert.Assert(t, userClient.IsReadOnly())
+
+	assertDenied := func(err error) {
+		t.Helper()
+		var routerErr rtypes.Error
+		assert.Assert(t, errors.As(err, &routerErr))
+		assert.Equal(t, types.ErrCodePermissionDenied, routerErr.Code)
+	}
+
+	var mutation struct {
+		DeleteUsers struct {
+			AffectedRows int `graphql:"affected_rows"`
+		} `graphql:"delete_users(where: {})"`
+	}
+	assertDenied(userClient.Mutate(context.Background(), &mutation, nil))
+	_, err = userClient.MutateRaw(context.Background(), &mutation, nil)
+	assertDenied(err)
+	_, err = userClient.ExecRaw(context.Background(), "query GetUsers { users { id } }\nmutation DeleteUsers { delete_users(where: {}) { affected_rows } }", nil)
+	assertDenied(err)
+	_, err = userClient.ExecRaw(context.Background(), "  # comment\n mutation { delete_users(where: {}) { affected_rows } }", nil)
+	assertDenied(err)
+	_, err = userClient.ExecRaw(context.Background(), "query { users(where: {name: {_eq: \"foo\"}) { id } }", nil)
+	assertDenied(err)
+	assert.Equal(t, int32(0), requests.Load())
+
+	var query struct {
+		Users []struct {
+			ID int `graphql:"id"`
+		} `graphql:"users"`
+	}
+	assert.NilError(t, userClient.Query(context.Background(), &query, nil))
+	_, err = userClient.ExecRaw(context.Background(), `query { users(where: {name: {_eq: "mutation"}}) { id } }`, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, int32(2), requests.Load())
+
+	// the option applies to the root client
+	_, err = NewAdminClient(server.URL, "secret", WithReadOnly()).MutateRaw(context.Background(), &mutation, nil)
+	assertDenied(err)
+	// the parent client isn't affected
+	_, err = client.MutateRaw(context.Background(), &mutation, nil)
+	assert.NilError(t, err)
+
+	// metadata writes are rejected
+	requestCount := requests.Load()
+	assertDenied(readOnlyClient.Metadata(context.Background(), MetadataRequest{Type: "run_sql", Args: map[string]any{"sql": "DELETE FROM users"}}, nil))
+	assertDenied(readOnlyClient.RedeliverEvent(context.Background(), "event-id"))
+	_, err = readOnlyClient.RedeliverFailedEvents(context.Background(), RedeliverFailedEventsInput{TriggerNames: []string{"users"}})
+	assertDenied(err)
+	assertDenied(readOnlyClient.Metadata(context.Background(), MetadataRequest{
+		Type: "bulk",
+		Args: []MetadataRequest{{Type: "export_metadata"}, {Type: "pg_delete_event_trigger"}},
+	}, nil))
+	assert.Equal(t, requestCount, requests.Load())
+	assert.NilError(t, readOnlyClient.Metadata(context.Background(), MetadataRequest{Type: "export_metadata", Args: map[string]any{}}, nil))
+	assert.NilError(t, readOnlyClient.Metadata(context.Background(), MetadataRequest{
+		Type: "bulk",
+		Args: []MetadataRequest{{Type: "export_metadata"}, {Type: "pg_get_event_logs"}, {Type: "get_scheduled_events"}},
+	}, nil))
+	assert.Equal(t, requestCount+2, requests.Load())
+}
@@ -0,0 +1,239 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hasura/go-graphql-client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type requestIDContextKey struct{}
+
+type requestStateContextKey struct{}
+
+// requestState records the response information of an operation from the http transport
+type requestState struct {
+	requestID         string
+	responseRequestID string
+	role              string
+	startedAt         time.Time
+	attempts          int
+	statusCode        int
+	header            http.Header
+	// sharedRequestID the request id of the leader request if the response is shared by singleflight
+	sharedRequestID string
+	// metadata is filled after the operation if the caller captures the response metadata
+	metadata *ResponseMetadata
+	body     *bytes.Buffer
+	// sent reports whether the request reached the http transport
+	sent bool
+	// dumpRequest enables the request dump, which debug mode, response errors and CallRequestDump need
+	dumpRequest bool
+	// request the masked dump of the last sent request
+	request *RequestDump
+	// dump is filled after the operation if the caller captures the request dump
+	dump *RequestDump
+}
+
+// NewRequestIDContext returns a context that carries the request id
+func NewRequestIDContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext gets the request id from the context
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDContextKey{}).(string)
+	return requestID, ok && requestID != ""
+}
+
+// RequestIDMiddleware reads the x-request-id header of incoming requests, or generates a new UUID,
+// sets it to the request context and echoes it in the response header
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(XRequestId)
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(XRequestId, requestID)
+		next.ServeHTTP(w, r.WithContext(NewRequestIDContext(r.Context(), requestID)))
+	})
+}
+
+func getRequestState(ctx context.Context) *requestState {
+	state, _ := ctx.Value(requestStateContextKey{}).(*requestState)
+	return state
+}
+
+// recordRequest marks the request as sent and captures the masked dump of the request if it is enabled
+func (rs *requestState) recordRequest(req *http.Request) {
+	rs.sent = true
+	if rs.dumpRequest {
+		rs.request = newRequestDump(req)
+	}
+}
+
+// recordResponse captures the request id that Hasura echoes back and the response metadata
+func (rs *requestState) recordResponse(resp *http.Response) {
+	rs.attempts++
+	rs.statusCode = resp.StatusCode
+	rs.header = maskResponseHeaders(resp.Header)
+	if requestID := resp.Header.Get(XRequestId); requestID != "" {
+		rs.responseRequestID = requestID
+	}
+	if rs.metadata != nil && resp.Body != nil {
+		rs.body = &bytes.Buffer{}
+		resp.Body = teeReadCloser{
+			Reader: io.TeeReader(resp.Body, rs.body),
+			Closer: resp.Body,
+		}
+	}
+}
+
+// detach creates the state of the shared request that outlives the leader call.
+// It always captures the response body, so that waiting calls can read extensions of the shared response
+func (rs *requestState) detach() *requestState {
+	return &requestState{
+		requestID:   rs.requestID,
+		role:        rs.role,
+		startedAt:   rs.startedAt,
+		metadata:    &ResponseMetadata{},
+		dumpRequest: rs.dumpRequest,
+	}
+}
+
+// shareResponse copies the recorded response of the shared request.
+// The leader call takes the sent request, waiting calls refer to it by the shared request id
+func (rs *requestState) shareResponse(shared *requestState, leader bool) {
+	rs.attempts = shared.attempts
+	rs.statusCode = shared.statusCode
+	rs.header = shared.header.Clone()
+	rs.body = shared.body
+	if leader {
+		rs.responseRequestID = shared.responseRequestID
+		rs.sent = shared.sent
+		rs.request = shared.request
+		return
+	}
+	rs.sharedRequestID = shared.requestID
+	if shared.responseRequestID != "" {
+		rs.sharedRequestID = shared.responseRequestID
+	}
+}
+
+// resolveRequestID sets the request id of the operation to session variables.
+// The explicit session variable takes precedence over the context, a new UUID is generated if none is present
+func (c *HasuraClient) resolveRequestID(ctx context.Context, sessionVariables SessionVariables) (string, SessionVariables) {
+	requestID := sessionVariables.GetRequestID()
+	if requestID == "" {
+		requestID, _ = RequestIDFromContext(ctx)
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		sessionVariables = sessionVariables.Clone()
+		sessionVariables.Set(XRequestId, requestID)
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("request_id", requestID))
+	return requestID, sessionVariables
+}
+
+// finishRequest attaches the request id that Hasura echoes back to the span and the extensions of GraphQL errors.
+// Errors of sent requests are wrapped with ResponseError that carries the response metadata and the request dump
+// if the client enables WithResponseErrors, otherwise the original error type is kept
+func (c *HasuraClient) finishRequest(ctx context.Context, span trace.Span, err error) error {
+	state := getRequestState(ctx)
+	if state == nil {
+		return err
+	}
+	requestID := state.requestID
+	if state.responseRequestID != "" && state.responseRequestID != requestID {
+		requestID = state.responseRequestID
+		span.SetAttributes(attribute.String("hasura_request_id", requestID))
+	}
+	if err != nil {
+		err = withRequestIDExtension(err, requestID)
+	}
+
+	var metadata ResponseMetadata
+	if state.metadata != nil || (err != nil && state.sent) {
+		metadata = state.buildMetadata(requestID)
+	}
+	if state.metadata != nil {
+		*state.metadata = metadata
+	}
+	if state.dump != nil && state.request != nil {
+		*state.dump = *state.request
+	}
+	// errors before the request is sent, e.g. query encoding errors, are returned as is
+	if err == nil || !state.sent || !c.responseErrors {
+		return err
+	}
+
+	return &ResponseError{
+		Metadata: metadata,
+		Request:  state.request,
+		Err:      err,
+	}
+}
+
+// withRequestIDExtension copies GraphQL errors with the request id in extensions.
+// The shared errors of singleflight calls aren't modified. Wrapped GraphQL errors are re-wrapped,
+// so the original error chain is kept
+func withRequestIDExtension(err error, requestID string) error {
+	var gqlErrors graphql.Errors
+	if !errors.As(err, &gqlErrors) {
+		return err
+	}
+	result := make(graphql.Errors, len(gqlErrors))
+	for i, e := range gqlErrors {
+		extensions := maps.Clone(e.Extensions)
+		if extensions == nil {
+			extensions = map[string]any{}
+		}
+		extensions["request_id"] = requestID
+		e.Extensions = extensions
+		result[i] = e
+	}
+
+	if _, ok := err.(graphql.Errors); ok {
+		return result
+	}
+	return &requestIDError{
+		errors: result,
+		err:    err,
+	}
+}
+
+// requestIDError wraps the error chain that contains GraphQL errors.
+// errors.As gets the copied GraphQL errors with the request id
+type requestIDError struct {
+	errors graphql.Errors
+	err    error
+}
+
+// Error implements the error interface
+func (re *requestIDError) Error() string {
+	return re.err.Error()
+}
+
+// Unwrap returns the original error
+func (re *requestIDError) Unwrap() error {
+	return re.err
+}
+
+// As sets the target to the GraphQL errors with the request id
+func (re *requestIDError) As(target any) bool {
+	gqlErrors, ok := target.(*graphql.Errors)
+	if ok {
+		*gqlErrors = re.errors
+	}
+	return ok
+}
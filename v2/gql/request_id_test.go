@@ -0,0 +1,92 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hasura/go-graphql-client"
+	"gotest.tools/v3/assert"
+)
+
+func TestHasuraClient_RequestID(t *testing.T) {
+	var requestIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(XRequestId)
+		requestIDs = append(requestIDs, requestID)
+		if requestID == "from-session" {
+			w.Header().Set(XRequestId, "hasura-id")
+		} else {
+			w.Header().Set(XRequestId, requestID)
+		}
+		_, _ = w.Write([]byte(`{"errors": [{"message": "field not found", "extensions": {"code": "validation-failed"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewHasuraClient(server.URL)
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := client.ExecRaw(r.Context(), "query { foo }", nil)
+		var gqlErrors graphql.Errors
+		assert.Assert(t, errors.As(err, &gqlErrors))
+		assert.Equal(t, "incoming-id", gqlErrors[0].Extensions["request_id"])
+		assert.Equal(t, "validation-failed", gqlErrors[0].Extensions["code"])
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-Request-Id", "incoming-id")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	response := recorder.Result()
+	assert.Equal(t, "incoming-id", response.Header.Get(XRequestId))
+	assert.Equal(t, "incoming-id", requestIDs[0])
+
+	_, err := client.ExecRaw(context.Background(), "query { foo }", nil)
+	var gqlErrors graphql.Errors
+	assert.Assert(t, errors.As(err, &gqlErrors))
+	_, err = uuid.Parse(requestIDs[1])
+	assert.NilError(t, err)
+	assert.Equal(t, requestIDs[1], gqlErrors[0].Extensions["request_id"])
+
+	_, err = client.ExecRaw(NewRequestIDContext(context.Background(), "from-context"), "query { foo }", nil, CallHeader(XRequestId, "from-session"))
+	assert.Assert(t, errors.As(err, &gqlErrors))
+	assert.Equal(t, "from-session", requestIDs[2])
+	assert.Equal(t, "hasura-id", gqlErrors[0].Extensions["request_id"])
+}
+
+type wrappedGraphQLError struct {
+	err error
+}
+
+func (we *wrappedGraphQLError) Error() string {
+	return "wrapped: " + we.err.Error()
+}
+
+func (we *wrappedGraphQLError) Unwrap() error {
+	return we.err
+}
+
+func TestWithRequestIDExtension(t *testing.T) {
+	errNotFound := errors.New("not found")
+	original := graphql.Errors{{Message: "boom"}}
+	err := fmt.Errorf("query failed: %w", &wrappedGraphQLError{err: errors.Join(errNotFound, original)})
+
+	result := withRequestIDExtension(err, "abc")
+	assert.Equal(t, err.Error(), result.Error())
+	assert.ErrorIs(t, result, errNotFound)
+	var wrapped *wrappedGraphQLError
+	assert.Assert(t, errors.As(result, &wrapped))
+	var gqlErrors graphql.Errors
+	assert.Assert(t, errors.As(result, &gqlErrors))
+	assert.Equal(t, "boom", gqlErrors[0].Message)
+	assert.Equal(t, "abc", gqlErrors[0].Extensions["request_id"])
+	// the original errors aren't modified
+	assert.Assert(t, original[0].Extensions == nil)
+
+	// unwrapped GraphQL errors keep the type
+	gqlErrors, ok := withRequestIDExtension(original, "abc").(graphql.Errors)
+	assert.Assert(t, ok)
+	assert.Equal(t, "abc", gqlErrors[0].Extensions["request_id"])
+}
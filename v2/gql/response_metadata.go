@@ -0,0 +1,106 @@
+package gql
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+// ResponseMetadata represents the metadata of the Hasura response of an operation
+type ResponseMetadata struct {
+	// StatusCode the HTTP status of the last response
+	StatusCode int
+	// Header the headers of the last response, Set-Cookie values are masked
+	Header http.Header
+	// Extensions the raw top-level extensions of the response body
+	Extensions json.RawMessage
+	// RequestID the request id that Hasura echoes back, or the sent request id
+	RequestID string
+	// SharedRequestID the request id of the leader request if the response is shared by singleflight
+	SharedRequestID string
+	// Duration the time elapsed from the start of the operation until the response is decoded
+	Duration time.Duration
+	// RetryCount the number of HTTP round trips after the first one, e.g. retries and redirects
+	RetryCount int
+}
+
+// ResponseError wraps the error of a sent operation with the response metadata and the request dump.
+// Operation errors are wrapped only if the client enables WithResponseErrors
+type ResponseError struct {
+	Metadata ResponseMetadata
+	// Request the replayable dump of the request with masked secrets
+	Request *RequestDump
+	Err     error
+}
+
+// Error implements the error interface
+func (re *ResponseError) Error() string {
+	return re.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (re *ResponseError) Unwrap() error {
+	return re.Err
+}
+
+// WithResponseErrors wraps errors of sent operations with ResponseError, so that the response metadata
+// and the request dump can be read from the error. Wrapped errors are matched by errors.As only,
+// direct type assertions such as err.(graphql.Errors) fail
+func WithResponseErrors() Option {
+	return func(opts *options) {
+		opts.responseErrors = true
+	}
+}
+
+// ResponseMetadataFromError gets the response metadata from the error of an operation if the client enables WithResponseErrors.
+// Extensions are available only if the operation captures the response metadata
+func ResponseMetadataFromError(err error) (*ResponseMetadata, bool) {
+	var responseErr *ResponseError
+	if !errors.As(err, &responseErr) {
+		return nil, false
+	}
+	return &responseErr.Metadata, true
+}
+
+// CallResponseMetadata captures the metadata of the response into the metadata struct,
+// including the top-level extensions of the response body
+func CallResponseMetadata(metadata *ResponseMetadata) graphql.Option {
+	return callOption{
+		apply: func(opts *callOptions) {
+			opts.responseMetadata = metadata
+		},
+	}
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// buildMetadata builds the response metadata from the recorded response
+func (rs *requestState) buildMetadata(requestID string) ResponseMetadata {
+	metadata := ResponseMetadata{
+		StatusCode:      rs.statusCode,
+		Header:          rs.header,
+		RequestID:       requestID,
+		SharedRequestID: rs.sharedRequestID,
+		Duration:        time.Since(rs.startedAt),
+	}
+	if rs.attempts > 1 {
+		metadata.RetryCount = rs.attempts - 1
+	}
+	if rs.body != nil && rs.body.Len() > 0 {
+		var body struct {
+			Extensions json.RawMessage `json:"extensions"`
+		}
+		if err := json.Unmarshal(rs.body.Bytes(), &body); err == nil && !isNullJSON(body.Extensions) {
+			metadata.Extensions = body.Extensions
+		}
+	}
+
+	return metadata
+}
@@ -0,0 +1,62 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+	"gotest.tools/v3/assert"
+)
+
+func TestHasuraClient_ResponseMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(XRequestId, "hasura-id")
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Set-Cookie", "session=token")
+		if r.URL.Path == "/error" {
+			_, _ = w.Write([]byte(`{"errors": [{"message": "field not found"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"__typename": "query_root"}, "extensions": {"cost": 10}}`))
+	}))
+	defer server.Close()
+
+	var metadata ResponseMetadata
+	var result struct {
+		Typename string `graphql:"__typename"`
+	}
+	err := NewHasuraClient(server.URL).Query(context.Background(), &result, nil, CallResponseMetadata(&metadata))
+	assert.NilError(t, err)
+	assert.Equal(t, "query_root", result.Typename)
+	assert.Equal(t, http.StatusOK, metadata.StatusCode)
+	assert.Equal(t, "max-age=60", metadata.Header.Get("Cache-Control"))
+	assert.Equal(t, MaskedValue, metadata.Header.Get("Set-Cookie"))
+	assert.Equal(t, "hasura-id", metadata.RequestID)
+	assert.Equal(t, `{"cost": 10}`, string(metadata.Extensions))
+	assert.Equal(t, 0, metadata.RetryCount)
+	assert.Assert(t, metadata.Duration > 0)
+
+	// errors keep the original type by default
+	_, err = NewHasuraClient(server.URL+"/error").ExecRaw(context.Background(), "query { foo }", nil, CallResponseMetadata(&metadata))
+	gqlErrs, ok := err.(graphql.Errors)
+	assert.Assert(t, ok)
+	assert.Equal(t, "hasura-id", gqlErrs[0].Extensions["request_id"])
+	assert.Equal(t, "hasura-id", metadata.RequestID)
+	_, ok = ResponseMetadataFromError(err)
+	assert.Assert(t, !ok)
+	_, ok = RequestDumpFromError(err)
+	assert.Assert(t, !ok)
+
+	_, err = NewHasuraClient(server.URL+"/error", WithResponseErrors()).ExecRaw(context.Background(), "query { foo }", nil, CallResponseMetadata(&metadata))
+	errMetadata, ok := ResponseMetadataFromError(err)
+	assert.Assert(t, ok)
+	assert.Equal(t, "hasura-id", errMetadata.RequestID)
+	assert.DeepEqual(t, metadata, *errMetadata)
+	var gqlErrors graphql.Errors
+	assert.Assert(t, errors.As(err, &gqlErrors))
+	assert.Equal(t, "field not found", gqlErrors[0].Message)
+	assert.Equal(t, "hasura-id", gqlErrors[0].Extensions["request_id"])
+}
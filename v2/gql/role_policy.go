@@ -0,0 +1,128 @@
+package gql
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// XHasuraImpersonator the conventional header that attributes requests of impersonated clients to the source client
+const XHasuraImpersonator = "x-hasura-impersonator"
+
+const packagePath = "github.com/hgiasac/hasura-utils/v2/gql."
+
+// ErrRoleEscalationDenied is returned when the role policy denies a role transition
+var ErrRoleEscalationDenied = errors.New("role escalation denied")
+
+// RoleTransition represents a role change of a derived client
+type RoleTransition struct {
+	// Method the name of the derivation method, e.g. AsRole
+	Method     string
+	ClientName string
+	FromRole   string
+	FromUserID string
+	ToRole     string
+	ToUserID   string
+	// Caller the file:line location of the code that requests the transition
+	Caller string
+	// Allowed is set for audit hooks after the policy is evaluated
+	Allowed bool
+	// Err the reason why the transition is denied
+	Err error
+}
+
+// RoleApprover approves or denies a role transition. Return a non-nil error to deny it
+type RoleApprover func(transition RoleTransition) error
+
+// RoleAuditHook receives every evaluated role transition, including denied ones
+type RoleAuditHook func(transition RoleTransition)
+
+type rolePolicy struct {
+	allowedRoles       []string
+	approver           RoleApprover
+	auditHook          RoleAuditHook
+	impersonatorHeader string
+}
+
+var defaultRolePolicy = rolePolicy{}
+
+// WithAllowedRoles restricts the roles that derived clients may assume, including admin
+func WithAllowedRoles(roles ...string) Option {
+	return func(opts *options) {
+		opts.rolePolicy.allowedRoles = append(opts.rolePolicy.allowedRoles, roles...)
+	}
+}
+
+// WithRoleApprover sets the callback that approves or denies each role transition
+func WithRoleApprover(approver RoleApprover) Option {
+	return func(opts *options) {
+		opts.rolePolicy.approver = approver
+	}
+}
+
+// WithRoleAuditHook sets the hook that receives every role transition
+func WithRoleAuditHook(hook RoleAuditHook) Option {
+	return func(opts *options) {
+		opts.rolePolicy.auditHook = hook
+	}
+}
+
+// WithImpersonatorHeader enables the header that impersonated clients send with the source client identity,
+// e.g. XHasuraImpersonator. Impersonated clients don't send the header by default, an empty name disables it
+func WithImpersonatorHeader(name string) Option {
+	return func(opts *options) {
+		opts.rolePolicy.impersonatorHeader = strings.ToLower(name)
+	}
+}
+
+// authorize evaluates the transition with the allowlist and the approver, then sends it to the audit hook
+func (rp *rolePolicy) authorize(transition RoleTransition) error {
+	if rp == nil {
+		return nil
+	}
+	transition.Caller = callerLocation()
+	if transition.ToRole != "" && len(rp.allowedRoles) > 0 && !slices.Contains(rp.allowedRoles, transition.ToRole) {
+		transition.Err = fmt.Errorf("%w: role <%s> is not allowed", ErrRoleEscalationDenied, transition.ToRole)
+	}
+	if transition.Err == nil && rp.approver != nil {
+		if err := rp.approver(transition); err != nil {
+			transition.Err = fmt.Errorf("%w: %w", ErrRoleEscalationDenied, err)
+		}
+	}
+	transition.Allowed = transition.Err == nil
+
+	if rp.auditHook != nil {
+		rp.auditHook(transition)
+	}
+
+	return transition.Err
+}
+
+// impersonator returns the identity of the source client for the impersonator header
+func (c *HasuraClient) impersonator() string {
+	if c.clientName != "" {
+		return c.clientName
+	}
+	if role := c.sessionVariables.GetRole(); role != "" {
+		return role
+	}
+	return RoleAdmin
+}
+
+// callerLocation returns the location of the first caller outside this package
+func callerLocation() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePath) || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
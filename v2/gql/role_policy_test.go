@@ -0,0 +1,85 @@
+package gql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRolePolicy(t *testing.T) {
+	var transitions []RoleTransition
+	client := NewAdminClient("http://localhost:8080/v1/graphql", "secret",
+		WithClientName("worker"),
+		WithImpersonatorHeader(XHasuraImpersonator),
+		WithAllowedRoles("user", "editor"),
+		WithRoleApprover(func(transition RoleTransition) error {
+			if transition.ToRole == "editor" && transition.ToUserID == "" {
+				return errors.New("editor requires an user id")
+			}
+			return nil
+		}),
+		WithRoleAuditHook(func(transition RoleTransition) {
+			transitions = append(transitions, transition)
+		}),
+	)
+
+	userClient, err := client.AsRole("user", "1")
+	assert.NilError(t, err)
+	assert.Equal(t, "worker", userClient.sessionVariables.Get(XHasuraImpersonator))
+
+	_, err = client.AsRole("editor", "")
+	assert.ErrorIs(t, err, ErrRoleEscalationDenied)
+	assert.ErrorContains(t, err, "editor requires an user id")
+
+	_, err = userClient.AsAdmin()
+	assert.ErrorIs(t, err, ErrRoleEscalationDenied)
+
+	_, err = client.As(map[string]string{XHasuraRole: "manager"})
+	assert.ErrorIs(t, err, ErrRoleEscalationDenied)
+
+	// dropping the role keeps the admin secret, so the derived client is an admin client
+	_, err = userClient.As(map[string]string{})
+	assert.ErrorIs(t, err, ErrRoleEscalationDenied)
+	_, err = userClient.With(WithoutHeader(XHasuraRole))
+	assert.ErrorIs(t, err, ErrRoleEscalationDenied)
+	assert.Equal(t, RoleAdmin, transitions[len(transitions)-1].ToRole)
+
+	assert.Equal(t, 6, len(transitions))
+	caller := transitions[0].Caller
+	transitions[0].Caller = ""
+	assert.DeepEqual(t, RoleTransition{
+		Method:     "AsRole",
+		ClientName: "worker",
+		FromRole:   RoleAdmin,
+		ToRole:     "user",
+		ToUserID:   "1",
+		Allowed:    true,
+	}, transitions[0])
+	assert.Assert(t, strings.Contains(caller, "role_policy_test.go"), caller)
+	assert.Assert(t, !transitions[1].Allowed)
+	assert.Equal(t, "user", transitions[2].FromRole)
+	assert.Equal(t, RoleAdmin, transitions[2].ToRole)
+}
+
+func TestRolePolicy_ImpersonatorHeader(t *testing.T) {
+	client := NewAdminClient("http://localhost:8080/v1/graphql", "secret", WithImpersonatorHeader("X-Impersonated-By"))
+	userClient, err := client.AsRole("user", "1")
+	assert.NilError(t, err)
+	assert.Equal(t, RoleAdmin, userClient.sessionVariables.Get("x-impersonated-by"))
+
+	adminClient, err := userClient.AsAdmin()
+	assert.NilError(t, err)
+	assert.Equal(t, "", adminClient.sessionVariables.Get("x-impersonated-by"))
+
+	// the header is opt-in
+	userClient, err = NewAdminClient("http://localhost:8080/v1/graphql", "secret").AsRole("user", "1")
+	assert.NilError(t, err)
+	assert.Equal(t, "", userClient.sessionVariables.Get(XHasuraImpersonator))
+
+	userClient, err = NewAdminClient("http://localhost:8080/v1/graphql", "secret",
+		WithImpersonatorHeader(XHasuraImpersonator), WithImpersonatorHeader("")).AsRole("user", "1")
+	assert.NilError(t, err)
+	assert.Equal(t, "", userClient.sessionVariables.Get(XHasuraImpersonator))
+}
@@ -0,0 +1,191 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// ScheduledEventType represents the type of scheduled events
+type ScheduledEventType string
+
+const (
+	ScheduledEventOneOff ScheduledEventType = "one_off"
+	ScheduledEventCron   ScheduledEventType = "cron"
+)
+
+// ScheduledEventStatus represents the delivery status of scheduled events
+type ScheduledEventStatus string
+
+const (
+	ScheduledEventScheduled ScheduledEventStatus = "scheduled"
+	ScheduledEventLocked    ScheduledEventStatus = "locked"
+	ScheduledEventDelivered ScheduledEventStatus = "delivered"
+	ScheduledEventError     ScheduledEventStatus = "error"
+	ScheduledEventDead      ScheduledEventStatus = "dead"
+)
+
+// HeaderConfig represents a header of webhook requests, the value can be a literal or read from an environment variable
+type HeaderConfig struct {
+	Name         string `json:"name"`
+	Value        string `json:"value,omitempty"`
+	ValueFromEnv string `json:"value_from_env,omitempty"`
+}
+
+// ScheduledRetryConfig represents the retry configuration of scheduled events
+type ScheduledRetryConfig struct {
+	NumRetries           int `json:"num_retries"`
+	RetryIntervalSeconds int `json:"retry_interval_seconds,omitempty"`
+	TimeoutSeconds       int `json:"timeout_seconds,omitempty"`
+	ToleranceSeconds     int `json:"tolerance_seconds,omitempty"`
+}
+
+// CreateScheduledEventInput represents the arguments of create_scheduled_event
+type CreateScheduledEventInput struct {
+	Webhook    string                `json:"webhook"`
+	ScheduleAt time.Time             `json:"schedule_at"`
+	Payload    any                   `json:"payload,omitempty"`
+	Headers    []HeaderConfig        `json:"headers,omitempty"`
+	RetryConf  *ScheduledRetryConfig `json:"retry_conf,omitempty"`
+	Comment    string                `json:"comment,omitempty"`
+}
+
+// CreateCronTriggerInput represents the arguments of create_cron_trigger
+type CreateCronTriggerInput struct {
+	Name              string                `json:"name"`
+	Webhook           string                `json:"webhook"`
+	Schedule          string                `json:"schedule"`
+	Payload           any                   `json:"payload,omitempty"`
+	Headers           []HeaderConfig        `json:"headers,omitempty"`
+	RetryConf         *ScheduledRetryConfig `json:"retry_conf,omitempty"`
+	IncludeInMetadata bool                  `json:"include_in_metadata"`
+	Comment           string                `json:"comment,omitempty"`
+	Replace           bool                  `json:"replace,omitempty"`
+}
+
+// GetScheduledEventsInput represents the arguments of get_scheduled_events
+type GetScheduledEventsInput struct {
+	Type         ScheduledEventType     `json:"type"`
+	TriggerName  string                 `json:"trigger_name,omitempty"`
+	Limit        int                    `json:"limit,omitempty"`
+	Offset       int                    `json:"offset,omitempty"`
+	GetRowsCount bool                   `json:"get_rows_count,omitempty"`
+	Status       []ScheduledEventStatus `json:"status,omitempty"`
+}
+
+// GetScheduledEventInvocationsInput represents the arguments of get_scheduled_event_invocations.
+// Set either the event id or the cron trigger name
+type GetScheduledEventInvocationsInput struct {
+	Type         ScheduledEventType `json:"type"`
+	EventID      string             `json:"event_id,omitempty"`
+	TriggerName  string             `json:"trigger_name,omitempty"`
+	Limit        int                `json:"limit,omitempty"`
+	Offset       int                `json:"offset,omitempty"`
+	GetRowsCount bool               `json:"get_rows_count,omitempty"`
+}
+
+// ScheduledEvent represents a scheduled event row
+type ScheduledEvent struct {
+	ID            string               `json:"id"`
+	Name          string               `json:"name,omitempty"`
+	WebhookConf   json.RawMessage      `json:"webhook_conf,omitempty"`
+	ScheduledTime time.Time            `json:"scheduled_time"`
+	Payload       json.RawMessage      `json:"payload,omitempty"`
+	Status        ScheduledEventStatus `json:"status"`
+	Tries         int                  `json:"tries"`
+	CreatedAt     time.Time            `json:"created_at"`
+	NextRetryAt   *time.Time           `json:"next_retry_at,omitempty"`
+	Comment       string               `json:"comment,omitempty"`
+}
+
+// ScheduledEventsOutput represents the response of get_scheduled_events
+type ScheduledEventsOutput struct {
+	Events []ScheduledEvent `json:"events"`
+	Count  int              `json:"count,omitempty"`
+}
+
+// ScheduledEventInvocation represents a delivery attempt of a scheduled event
+type ScheduledEventInvocation struct {
+	ID        string          `json:"id"`
+	EventID   string          `json:"event_id"`
+	Status    int             `json:"status"`
+	Request   json.RawMessage `json:"request"`
+	Response  json.RawMessage `json:"response"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ScheduledEventInvocationsOutput represents the response of get_scheduled_event_invocations
+type ScheduledEventInvocationsOutput struct {
+	Invocations []ScheduledEventInvocation `json:"invocations"`
+	Count       int                        `json:"count,omitempty"`
+}
+
+// CreateScheduledEvent creates a one-off scheduled event and returns the event id
+func (c *HasuraClient) CreateScheduledEvent(ctx context.Context, input CreateScheduledEventInput) (string, error) {
+	var result struct {
+		EventID string `json:"event_id"`
+	}
+	if err := c.Metadata(ctx, MetadataRequest{
+		Type: "create_scheduled_event",
+		Args: input,
+	}, &result); err != nil {
+		return "", err
+	}
+
+	return result.EventID, nil
+}
+
+// DeleteScheduledEvent deletes a one-off or cron scheduled event by id
+func (c *HasuraClient) DeleteScheduledEvent(ctx context.Context, eventType ScheduledEventType, eventID string) error {
+	return c.Metadata(ctx, MetadataRequest{
+		Type: "delete_scheduled_event",
+		Args: map[string]any{
+			"type":     eventType,
+			"event_id": eventID,
+		},
+	}, nil)
+}
+
+// GetScheduledEvents lists one-off or cron scheduled events
+func (c *HasuraClient) GetScheduledEvents(ctx context.Context, input GetScheduledEventsInput) (*ScheduledEventsOutput, error) {
+	var result ScheduledEventsOutput
+	if err := c.Metadata(ctx, MetadataRequest{
+		Type: "get_scheduled_events",
+		Args: input,
+	}, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetScheduledEventInvocations lists the delivery attempts of a scheduled event or cron trigger
+func (c *HasuraClient) GetScheduledEventInvocations(ctx context.Context, input GetScheduledEventInvocationsInput) (*ScheduledEventInvocationsOutput, error) {
+	var result ScheduledEventInvocationsOutput
+	if err := c.Metadata(ctx, MetadataRequest{
+		Type: "get_scheduled_event_invocations",
+		Args: input,
+	}, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// CreateCronTrigger creates a cron trigger
+func (c *HasuraClient) CreateCronTrigger(ctx context.Context, input CreateCronTriggerInput) error {
+	return c.Metadata(ctx, MetadataRequest{
+		Type: "create_cron_trigger",
+		Args: input,
+	}, nil)
+}
+
+// DeleteCronTrigger deletes a cron trigger by name
+func (c *HasuraClient) DeleteCronTrigger(ctx context.Context, name string) error {
+	return c.Metadata(ctx, MetadataRequest{
+		Type: "delete_cron_trigger",
+		Args: map[string]any{
+			"name": name,
+		},
+	}, nil)
+}
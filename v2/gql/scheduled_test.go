@@ -0,0 +1,47 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCreateScheduledEvent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Check(t, r.URL.Path == "/v1/metadata")
+		assert.Check(t, r.Header.Get(XHasuraAdminSecret) == "secret")
+
+		var body map[string]any
+		assert.Check(t, json.NewDecoder(r.Body).Decode(&body))
+		switch body["type"] {
+		case "create_scheduled_event":
+			args := body["args"].(map[string]any)
+			assert.Check(t, args["schedule_at"] == "2024-05-01T10:00:00Z")
+			_, _ = w.Write([]byte(`{"message": "success", "event_id": "abc"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"path": "$.args", "error": "cron trigger not found", "code": "not-exists"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewAdminClient(server.URL+"/v1/graphql", "secret")
+	eventID, err := client.CreateScheduledEvent(context.Background(), CreateScheduledEventInput{
+		Webhook:    "http://localhost/reminder",
+		ScheduleAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Payload:    map[string]string{"email": "foo@example.com"},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, "abc", eventID)
+
+	err = client.DeleteCronTrigger(context.Background(), "daily")
+	assert.ErrorContains(t, err, "not-exists: cron trigger not found")
+
+	_, err = NewHasuraClient(server.URL+"/v1/graphql").CreateScheduledEvent(context.Background(), CreateScheduledEventInput{})
+	assert.ErrorIs(t, err, errMetadataAdminRequired)
+}
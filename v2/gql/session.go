@@ -1,8 +1,14 @@
 package gql
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hgiasac/hasura-utils/utils"
 )
 
 // SessionVariables represents hasura session variables map
@@ -18,7 +24,8 @@ func NewSessionVariables(input map[string]string) SessionVariables {
 	return result
 }
 
-// NewSessionVariables parse and create a session variables instance from http header
+// NewSessionVariables parse and create a session variables instance from http header.
+// All headers are copied, use NewSanitizedSessionVariablesFromHeaders for untrusted requests
 func NewSessionVariablesFromHeaders(header http.Header) SessionVariables {
 	result := SessionVariables{}
 	for k, v := range header {
@@ -98,3 +105,145 @@ func (sv SessionVariables) Get(key string) string {
 func (sv SessionVariables) ToStringMap() map[string]string {
 	return sv
 }
+
+// ErrSessionVariableNotFound is returned by typed getters if the session variable doesn't exist
+var ErrSessionVariableNotFound = errors.New("session variable not found")
+
+// SessionVariableError represents a malformed session variable value
+type SessionVariableError struct {
+	Name  string
+	Value string
+	Err   error
+}
+
+// Error implements the error interface
+func (sve *SessionVariableError) Error() string {
+	if errors.Is(sve.Err, ErrSessionVariableNotFound) {
+		return fmt.Sprintf("%s: %s", sve.Name, sve.Err)
+	}
+	return fmt.Sprintf("invalid session variable %s=%q: %s", sve.Name, sve.Value, sve.Err)
+}
+
+// Unwrap returns the cause error
+func (sve *SessionVariableError) Unwrap() error {
+	return sve.Err
+}
+
+func (sv SessionVariables) lookup(key string) (string, error) {
+	key = strings.ToLower(key)
+	value, ok := sv[key]
+	if !ok {
+		return "", &SessionVariableError{Name: key, Err: ErrSessionVariableNotFound}
+	}
+	return value, nil
+}
+
+// GetInt gets and parses the session variable as an integer
+func (sv SessionVariables) GetInt(key string) (int, error) {
+	value, err := sv.lookup(key)
+	if err != nil {
+		return 0, err
+	}
+	result, err := strconv.ParseInt(value, 10, strconv.IntSize)
+	if err != nil {
+		return 0, &SessionVariableError{Name: strings.ToLower(key), Value: value, Err: err}
+	}
+	return int(result), nil
+}
+
+// GetInt64 gets and parses the session variable as a 64-bit integer
+func (sv SessionVariables) GetInt64(key string) (int64, error) {
+	value, err := sv.lookup(key)
+	if err != nil {
+		return 0, err
+	}
+	result, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, &SessionVariableError{Name: strings.ToLower(key), Value: value, Err: err}
+	}
+	return result, nil
+}
+
+// GetBool gets and parses the session variable as a boolean
+func (sv SessionVariables) GetBool(key string) (bool, error) {
+	value, err := sv.lookup(key)
+	if err != nil {
+		return false, err
+	}
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, &SessionVariableError{Name: strings.ToLower(key), Value: value, Err: err}
+	}
+	return result, nil
+}
+
+// GetUUID gets and parses the session variable as an UUID
+func (sv SessionVariables) GetUUID(key string) (uuid.UUID, error) {
+	value, err := sv.lookup(key)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	result, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, &SessionVariableError{Name: strings.ToLower(key), Value: value, Err: err}
+	}
+	return result, nil
+}
+
+// GetStringArray gets and decodes the session variable as a Postgres array, e.g. {user,editor}
+func (sv SessionVariables) GetStringArray(key string) ([]string, error) {
+	value, err := sv.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	result, err := utils.DecodePostgresArray(value)
+	if err != nil {
+		return nil, &SessionVariableError{Name: strings.ToLower(key), Value: value, Err: err}
+	}
+	return result, nil
+}
+
+// GetAllowedRoles gets the allowed roles of the session
+func (sv SessionVariables) GetAllowedRoles() ([]string, error) {
+	return sv.GetStringArray(XHasuraAllowedRoles)
+}
+
+// GetDefaultRole gets the default role of the session
+func (sv SessionVariables) GetDefaultRole() string {
+	return sv.Get(XHasuraDefaultRole)
+}
+
+// SetInt sets an integer session variable value
+func (sv *SessionVariables) SetInt(key string, value int) {
+	sv.Set(key, strconv.Itoa(value))
+}
+
+// SetInt64 sets a 64-bit integer session variable value
+func (sv *SessionVariables) SetInt64(key string, value int64) {
+	sv.Set(key, strconv.FormatInt(value, 10))
+}
+
+// SetBool sets a boolean session variable value
+func (sv *SessionVariables) SetBool(key string, value bool) {
+	sv.Set(key, strconv.FormatBool(value))
+}
+
+// SetUUID sets an UUID session variable value
+func (sv *SessionVariables) SetUUID(key string, value uuid.UUID) {
+	sv.Set(key, value.String())
+}
+
+// SetStringArray sets a session variable value with the Postgres array format
+func (sv *SessionVariables) SetStringArray(key string, values []string) {
+	sv.Set(key, utils.EncodePostgresArray(values))
+}
+
+// SetAllowedRoles sets the allowed roles of the session
+func (sv *SessionVariables) SetAllowedRoles(roles ...string) {
+	sv.SetStringArray(XHasuraAllowedRoles, roles)
+}
+
+// SetDefaultRole sets the default role of the session
+func (sv *SessionVariables) SetDefaultRole(role string) {
+	sv.Set(XHasuraDefaultRole, role)
+}
@@ -0,0 +1,169 @@
+package gql
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const hasuraSessionPrefix = "x-hasura-"
+
+// DefaultMaxSessionValueLength the default maximum length of session variable values
+const DefaultMaxSessionValueLength = 4096
+
+// ErrRoleNotAllowed is returned when the role isn't in the allowed roles of the session
+var ErrRoleNotAllowed = errors.New("role is not in the allowed roles")
+
+// SanitizeReason represents the reason why a session variable was removed
+type SanitizeReason string
+
+const (
+	SanitizeReasonNotHasura    SanitizeReason = "not_hasura_variable"
+	SanitizeReasonAdminSecret  SanitizeReason = "admin_secret"
+	SanitizeReasonTooLong      SanitizeReason = "value_too_long"
+	SanitizeReasonInvalidChars SanitizeReason = "invalid_characters"
+	// SanitizeReasonUntrustedRoles the allowed and default roles of untrusted input
+	SanitizeReasonUntrustedRoles SanitizeReason = "untrusted_roles"
+)
+
+// RemovedSessionVariable represents a session variable that was removed by the sanitizer.
+// The value is never reported because it may be sensitive
+type RemovedSessionVariable struct {
+	Name   string
+	Reason SanitizeReason
+}
+
+// SanitizeReport represents the result of the sanitization
+type SanitizeReport struct {
+	Removed []RemovedSessionVariable
+}
+
+// HasRemoved checks if any session variable was removed
+func (sr SanitizeReport) HasRemoved() bool {
+	return len(sr.Removed) > 0
+}
+
+type sanitizeOptions struct {
+	hasuraOnly     bool
+	allowlist      []string
+	untrusted      bool
+	validateRole   bool
+	allowedRoles   []string
+	maxValueLength int
+}
+
+// SanitizeOption the optional setting function of the session variables sanitizer
+type SanitizeOption func(*sanitizeOptions)
+
+// SanitizeHasuraOnly keeps only x-hasura-* variables and the allowlist, e.g. x-request-id
+func SanitizeHasuraOnly(allowlist ...string) SanitizeOption {
+	return func(opts *sanitizeOptions) {
+		opts.hasuraOnly = true
+		for _, name := range allowlist {
+			opts.allowlist = append(opts.allowlist, strings.ToLower(name))
+		}
+	}
+}
+
+// SanitizeUntrusted strips the admin secret, x-hasura-allowed-roles and x-hasura-default-role from untrusted input
+func SanitizeUntrusted() SanitizeOption {
+	return func(opts *sanitizeOptions) {
+		opts.untrusted = true
+	}
+}
+
+// SanitizeRoleCheck rejects the role that isn't in the allowed roles of a trusted source,
+// e.g. allowed roles of verified JWT claims. Roles are denied if the allowed roles are empty.
+// Allowed roles of the sanitized input are never trusted
+func SanitizeRoleCheck(allowedRoles ...string) SanitizeOption {
+	return func(opts *sanitizeOptions) {
+		opts.validateRole = true
+		opts.allowedRoles = append(opts.allowedRoles, allowedRoles...)
+	}
+}
+
+// SanitizeMaxValueLength sets the maximum length of values, default 4096
+func SanitizeMaxValueLength(length int) SanitizeOption {
+	return func(opts *sanitizeOptions) {
+		opts.maxValueLength = length
+	}
+}
+
+// Sanitize creates a filtered copy of session variables and reports removed variables.
+// Values with control characters or longer than the limit are always removed
+func (sv SessionVariables) Sanitize(options ...SanitizeOption) (SessionVariables, *SanitizeReport, error) {
+	opts := sanitizeOptions{
+		maxValueLength: DefaultMaxSessionValueLength,
+	}
+	for _, apply := range options {
+		apply(&opts)
+	}
+
+	result := SessionVariables{}
+	report := &SanitizeReport{}
+	for key, value := range sv {
+		name := strings.ToLower(key)
+		reason := opts.check(name, value)
+		if reason != "" {
+			report.Removed = append(report.Removed, RemovedSessionVariable{
+				Name:   name,
+				Reason: reason,
+			})
+			continue
+		}
+		result[name] = value
+	}
+	slices.SortFunc(report.Removed, func(a, b RemovedSessionVariable) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	if opts.validateRole {
+		if err := result.validateRole(opts.allowedRoles); err != nil {
+			return nil, report, err
+		}
+	}
+
+	return result, report, nil
+}
+
+// NewSanitizedSessionVariablesFromHeaders creates session variables from untrusted http headers.
+// Only x-hasura-* headers and the allowlist are kept, the admin secret and untrusted roles are stripped
+// and the role must be in the allowed roles of a trusted source. The role is denied if allowed roles are empty
+func NewSanitizedSessionVariablesFromHeaders(header http.Header, allowedRoles []string, allowlist ...string) (SessionVariables, *SanitizeReport, error) {
+	return NewSessionVariablesFromHeaders(header).Sanitize(
+		SanitizeHasuraOnly(allowlist...),
+		SanitizeUntrusted(),
+		SanitizeRoleCheck(allowedRoles...),
+	)
+}
+
+func (opts sanitizeOptions) check(name string, value string) SanitizeReason {
+	if opts.untrusted && name == XHasuraAdminSecret {
+		return SanitizeReasonAdminSecret
+	}
+	if opts.untrusted && (name == XHasuraAllowedRoles || name == XHasuraDefaultRole) {
+		return SanitizeReasonUntrustedRoles
+	}
+	if opts.hasuraOnly && !strings.HasPrefix(name, hasuraSessionPrefix) && !slices.Contains(opts.allowlist, name) {
+		return SanitizeReasonNotHasura
+	}
+	if opts.maxValueLength > 0 && len(value) > opts.maxValueLength {
+		return SanitizeReasonTooLong
+	}
+	if strings.ContainsFunc(value, func(r rune) bool {
+		return r < 0x20 || r == 0x7f
+	}) {
+		return SanitizeReasonInvalidChars
+	}
+	return ""
+}
+
+func (sv SessionVariables) validateRole(allowedRoles []string) error {
+	role := sv.GetRole()
+	if role == "" || slices.Contains(allowedRoles, role) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrRoleNotAllowed, role)
+}
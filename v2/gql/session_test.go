@@ -0,0 +1,112 @@
+package gql
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gotest.tools/v3/assert"
+)
+
+func TestSessionVariables_TypedAccessors(t *testing.T) {
+	id := uuid.New()
+	sv := SessionVariables{}
+	sv.SetInt64("X-Hasura-User-Id", 42)
+	sv.SetBool("x-hasura-is-owner", true)
+	sv.SetUUID("x-hasura-org-id", id)
+	sv.SetAllowedRoles("user", "editor")
+	sv.SetDefaultRole("user")
+
+	assert.DeepEqual(t, SessionVariables{
+		XHasuraUserID:       "42",
+		"x-hasura-is-owner": "true",
+		"x-hasura-org-id":   id.String(),
+		XHasuraAllowedRoles: "{user,editor}",
+		XHasuraDefaultRole:  "user",
+	}, sv)
+
+	userID, err := sv.GetInt(XHasuraUserID)
+	assert.NilError(t, err)
+	assert.Equal(t, 42, userID)
+
+	isOwner, err := sv.GetBool("X-Hasura-Is-Owner")
+	assert.NilError(t, err)
+	assert.Assert(t, isOwner)
+
+	orgID, err := sv.GetUUID("x-hasura-org-id")
+	assert.NilError(t, err)
+	assert.Equal(t, id, orgID)
+
+	roles, err := sv.GetAllowedRoles()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"user", "editor"}, roles)
+	assert.Equal(t, "user", sv.GetDefaultRole())
+
+	_, err = sv.GetInt("x-hasura-org-id")
+	var svErr *SessionVariableError
+	assert.Assert(t, errors.As(err, &svErr))
+	assert.Equal(t, "x-hasura-org-id", svErr.Name)
+	assert.ErrorContains(t, err, "invalid session variable x-hasura-org-id")
+
+	sv.Set(XHasuraAllowedRoles, "user")
+	_, err = sv.GetAllowedRoles()
+	assert.ErrorContains(t, err, "invalid session variable x-hasura-allowed-roles")
+
+	_, err = sv.GetInt64("x-hasura-tenant-id")
+	assert.ErrorIs(t, err, ErrSessionVariableNotFound)
+
+	sv.Set("x-hasura-count", "9223372036854775808")
+	_, err = sv.GetInt("x-hasura-count")
+	assert.ErrorIs(t, err, strconv.ErrRange)
+
+	sv.SetStringArray("x-hasura-groups", []string{"a b", `c,"d"`})
+	assert.Equal(t, `{"a b","c,\"d\""}`, sv.Get("x-hasura-groups"))
+	groups, err := sv.GetStringArray("x-hasura-groups")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"a b", `c,"d"`}, groups)
+}
+
+func TestSessionVariables_Sanitize(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer token")
+	header.Set("Cookie", "session=1")
+	header.Set("X-Request-Id", "abc")
+	header.Set("X-Hasura-Admin-Secret", "secret")
+	header.Set("X-Hasura-Role", "user")
+	header.Set("X-Hasura-Allowed-Roles", "{user,editor}")
+	header.Set("X-Hasura-User-Id", strings.Repeat("1", DefaultMaxSessionValueLength+1))
+
+	sv, report, err := NewSanitizedSessionVariablesFromHeaders(header, []string{"user"}, "X-Request-Id")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, SessionVariables{
+		XRequestId:  "abc",
+		XHasuraRole: "user",
+	}, sv)
+	assert.DeepEqual(t, []RemovedSessionVariable{
+		{Name: "authorization", Reason: SanitizeReasonNotHasura},
+		{Name: "cookie", Reason: SanitizeReasonNotHasura},
+		{Name: XHasuraAdminSecret, Reason: SanitizeReasonAdminSecret},
+		{Name: XHasuraAllowedRoles, Reason: SanitizeReasonUntrustedRoles},
+		{Name: XHasuraUserID, Reason: SanitizeReasonTooLong},
+	}, report.Removed)
+
+	// allowed roles of the untrusted headers are ignored
+	header.Set("X-Hasura-Role", "editor")
+	_, _, err = NewSanitizedSessionVariablesFromHeaders(header, []string{"user"})
+	assert.ErrorIs(t, err, ErrRoleNotAllowed)
+	// roles are denied without trusted allowed roles
+	header.Del("X-Hasura-Allowed-Roles")
+	_, _, err = NewSanitizedSessionVariablesFromHeaders(header, nil)
+	assert.ErrorIs(t, err, ErrRoleNotAllowed)
+	header.Del("X-Hasura-Role")
+	_, _, err = NewSanitizedSessionVariablesFromHeaders(header, nil)
+	assert.NilError(t, err)
+
+	sv, report, err = SessionVariables{"x-hasura-user-id": "1\r\nfoo"}.Sanitize()
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(sv))
+	assert.Equal(t, SanitizeReasonInvalidChars, report.Removed[0].Reason)
+}
@@ -0,0 +1,97 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithSingleflight coalesces identical in-flight Query and QueryRaw calls of the client and its derived clients.
+// Calls are identical if the document, variables and session variables, except the request id, match.
+// Waiting calls share the response of the leader request and decode it separately.
+// The shared request isn't canceled with the leader call, every call stops waiting when its own context is done.
+// Only the leader request is sent, so waiting calls keep their own request id and have no request dump.
+// Their response metadata describes the shared response and refers to the sent request by SharedRequestID
+func WithSingleflight() Option {
+	return func(opts *options) {
+		opts.singleflight = true
+	}
+}
+
+// flightCall represents an in-flight request that waiting calls share
+type flightCall struct {
+	done        chan struct{}
+	data        []byte
+	err         error
+	spanContext trace.SpanContext
+	// state records the response of the shared request
+	state *requestState
+}
+
+// flightGroup de-duplicates in-flight requests by key
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+func newFlightGroup(enabled bool) *flightGroup {
+	if !enabled {
+		return nil
+	}
+	return &flightGroup{
+		calls: map[string]*flightCall{},
+	}
+}
+
+// do executes the function once for concurrent calls of the same key.
+// The function runs with the context of the leader call without cancellation, so the leader call can stop waiting
+// without failing waiting calls. Waiting calls link their spans to the span of the leader request
+func (fg *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	fg.mu.Lock()
+	call, shared := fg.calls[key]
+	if shared {
+		fg.mu.Unlock()
+
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(attribute.Bool("singleflight_shared", true))
+		span.AddLink(trace.Link{
+			SpanContext: call.spanContext,
+			Attributes:  []attribute.KeyValue{attribute.String("link_type", "singleflight_leader")},
+		})
+	} else {
+		call = &flightCall{
+			done:        make(chan struct{}),
+			spanContext: trace.SpanContextFromContext(ctx),
+		}
+		fg.calls[key] = call
+		fg.mu.Unlock()
+
+		fetchCtx := context.WithoutCancel(ctx)
+		if state := getRequestState(ctx); state != nil {
+			call.state = state.detach()
+			fetchCtx = context.WithValue(fetchCtx, requestStateContextKey{}, call.state)
+		}
+		go func() {
+			defer func() {
+				fg.mu.Lock()
+				delete(fg.calls, key)
+				fg.mu.Unlock()
+				close(call.done)
+			}()
+			call.data, call.err = fn(fetchCtx)
+		}()
+	}
+
+	select {
+	case <-call.done:
+		if state := getRequestState(ctx); state != nil && call.state != nil {
+			state.shareResponse(call.state, !shared)
+		}
+		return bytes.Clone(call.data), call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
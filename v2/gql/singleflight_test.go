@@ -0,0 +1,111 @@
+package gql
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestHasuraClient_Singleflight(t *testing.T) {
+	var requests atomic.Int32
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		started <- struct{}{}
+		<-release
+		_, _ = w.Write([]byte(`{"data": {"flags": [{"name": "foo"}]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewAdminClient(server.URL, "secret", WithSingleflight()).AsRole("user", "1")
+	assert.NilError(t, err)
+
+	type flagsQuery struct {
+		Flags []struct {
+			Name string `graphql:"name"`
+		} `graphql:"flags"`
+	}
+	results := make([]flagsQuery, 5)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.Check(t, client.Query(context.Background(), &results[i], nil))
+		}(i)
+	}
+
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), requests.Load())
+	for _, result := range results {
+		assert.Equal(t, "foo", result.Flags[0].Name)
+	}
+
+	// different session variables aren't coalesced
+	anotherUser, err := client.AsRole("user", "2")
+	assert.NilError(t, err)
+	var result flagsQuery
+	assert.NilError(t, anotherUser.Query(context.Background(), &result, nil))
+	assert.NilError(t, client.Query(context.Background(), &result, nil))
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestHasuraClient_SingleflightLeaderCanceled(t *testing.T) {
+	var requests atomic.Int32
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		started <- struct{}{}
+		<-release
+		w.Header().Set(XRequestId, r.Header.Get(XRequestId))
+		_, _ = w.Write([]byte(`{"data": {"flags": [{"name": "foo"}]}}`))
+	}))
+	defer server.Close()
+
+	client := NewAdminClient(server.URL, "secret", WithSingleflight())
+
+	type flagsQuery struct {
+		Flags []struct {
+			Name string `graphql:"name"`
+		} `graphql:"flags"`
+	}
+
+	leaderCtx, cancel := context.WithCancel(NewRequestIDContext(context.Background(), "leader"))
+	leaderErr := make(chan error, 1)
+	go func() {
+		var result flagsQuery
+		leaderErr <- client.Query(leaderCtx, &result, nil)
+	}()
+	<-started
+
+	var result flagsQuery
+	var metadata ResponseMetadata
+	followerErr := make(chan error, 1)
+	go func() {
+		followerErr <- client.Query(NewRequestIDContext(context.Background(), "follower"), &result, nil, CallResponseMetadata(&metadata))
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+
+	close(release)
+	assert.NilError(t, <-followerErr)
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Equal(t, "foo", result.Flags[0].Name)
+	assert.Equal(t, http.StatusOK, metadata.StatusCode)
+	assert.Equal(t, "follower", metadata.RequestID)
+	assert.Equal(t, "leader", metadata.SharedRequestID)
+}
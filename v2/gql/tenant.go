@@ -0,0 +1,196 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/hgiasac/graphql-utils/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// XHasuraTenantID the session variable that carries the tenant of the request
+const XHasuraTenantID = "x-hasura-tenant-id"
+
+type tenantContextKey struct{}
+
+// ErrTenantRequired is returned when the operation of a multi-tenant client has no tenant
+var ErrTenantRequired = errors.New("tenant is required")
+
+// TenantError represents a failure to resolve the tenant or the tenant config of an operation
+type TenantError struct {
+	TenantID string
+	Err      error
+}
+
+// Error implements the error interface
+func (te *TenantError) Error() string {
+	if te.TenantID == "" {
+		return fmt.Sprintf("tenant error: %s", te.Err)
+	}
+	return fmt.Sprintf("tenant <%s> error: %s", te.TenantID, te.Err)
+}
+
+// Unwrap returns the underlying error
+func (te *TenantError) Unwrap() error {
+	return te.Err
+}
+
+// TenantResolver resolves the tenant id from the context. Return an empty string if the context has no tenant
+type TenantResolver func(ctx context.Context) (string, error)
+
+// TenantConfig overrides the endpoint and the admin secret of a tenant. Empty fields use the client settings
+type TenantConfig struct {
+	Endpoint    string
+	AdminSecret string
+}
+
+// TenantConfigResolver resolves the config of the tenant. Return nil to use the client settings
+type TenantConfigResolver func(ctx context.Context, tenantID string) (*TenantConfig, error)
+
+// NewTenantContext returns a context that carries the tenant id
+func NewTenantContext(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// TenantIDFromContext gets the tenant id from the context
+func TenantIDFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+// ContextTenantResolver the tenant resolver that reads the tenant id set by NewTenantContext
+func ContextTenantResolver(ctx context.Context) (string, error) {
+	tenantID, _ := TenantIDFromContext(ctx)
+	return tenantID, nil
+}
+
+type tenantOptions struct {
+	resolver       TenantResolver
+	configResolver TenantConfigResolver
+	crossTenant    bool
+	sessionTenant  bool
+}
+
+// WithTenantResolver enables multi-tenancy. The tenant of every operation is resolved from the context
+// and sent as the x-hasura-tenant-id session variable. Operations without a tenant fail with ErrTenantRequired.
+// The x-hasura-tenant-id session variable of the client or the call is ignored unless WithSessionTenant is set
+func WithTenantResolver(resolver TenantResolver) Option {
+	return func(opts *options) {
+		opts.tenant.resolver = resolver
+	}
+}
+
+// WithTenantConfigResolver sets the resolver of the per-tenant endpoint and admin secret
+func WithTenantConfigResolver(resolver TenantConfigResolver) Option {
+	return func(opts *options) {
+		opts.tenant.configResolver = resolver
+	}
+}
+
+// WithSessionTenant uses the x-hasura-tenant-id session variable of the client or the call
+// if the resolver returns no tenant
+func WithSessionTenant() Option {
+	return func(opts *options) {
+		opts.tenant.sessionTenant = true
+	}
+}
+
+// WithCrossTenant allows operations without a tenant when the client is in admin mode
+func WithCrossTenant() Option {
+	return func(opts *options) {
+		opts.tenant.crossTenant = true
+	}
+}
+
+type tenancy struct {
+	tenantOptions
+	debug      bool
+	httpClient *http.Client
+	// clients caches GraphQL clients of tenant endpoints
+	clients sync.Map
+}
+
+func newTenancy(opts options, httpClient *http.Client) *tenancy {
+	if opts.tenant.resolver == nil {
+		return nil
+	}
+	return &tenancy{
+		tenantOptions: opts.tenant,
+		debug:         opts.debug,
+		httpClient:    httpClient,
+	}
+}
+
+func (t *tenancy) client(endpoint string) client.Client {
+	if c, ok := t.clients.Load(endpoint); ok {
+		return c.(client.Client)
+	}
+	c, _ := t.clients.LoadOrStore(endpoint, client.NewClient(endpoint, t.httpClient).WithDebug(t.debug))
+	return c.(client.Client)
+}
+
+// isAdmin checks if the client acts as the admin
+func (c *HasuraClient) isAdmin() bool {
+	return isAdminSession(c.sessionVariables)
+}
+
+// isAdminSession checks if the session variables act as the admin
+func isAdminSession(sessionVariables SessionVariables) bool {
+	return sessionVariables.Get(XHasuraAdminSecret) != "" && effectiveRole(sessionVariables) == RoleAdmin
+}
+
+// resolveTenant sets the tenant id to session variables and returns the GraphQL client and the endpoint of the tenant
+func (c *HasuraClient) resolveTenant(ctx context.Context, sessionVariables SessionVariables) (client.Client, string, SessionVariables, error) {
+	if c.tenancy == nil {
+		return c.Client, c.endpoint, sessionVariables, nil
+	}
+
+	tenantID, err := c.tenancy.resolver(ctx)
+	if err != nil {
+		return nil, "", nil, &TenantError{Err: err}
+	}
+	if tenantID == "" && c.tenancy.sessionTenant {
+		tenantID = sessionVariables.Get(XHasuraTenantID)
+	}
+	if tenantID == "" {
+		if c.tenancy.crossTenant && isAdminSession(sessionVariables) {
+			if sessionVariables.Get(XHasuraTenantID) != "" {
+				sessionVariables = sessionVariables.Clone()
+				sessionVariables.Del(XHasuraTenantID)
+			}
+			return c.Client, c.endpoint, sessionVariables, nil
+		}
+		return nil, "", nil, &TenantError{Err: ErrTenantRequired}
+	}
+
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("tenant_id", tenantID))
+
+	sessionVariables = sessionVariables.Clone()
+	sessionVariables.Set(XHasuraTenantID, tenantID)
+	if c.tenancy.configResolver == nil {
+		return c.Client, c.endpoint, sessionVariables, nil
+	}
+
+	config, err := c.tenancy.configResolver(ctx, tenantID)
+	if err != nil {
+		return nil, "", nil, &TenantError{TenantID: tenantID, Err: err}
+	}
+	if config == nil {
+		return c.Client, c.endpoint, sessionVariables, nil
+	}
+	// the tenant secret replaces the client secret only, so clients without the admin secret aren't promoted
+	if config.AdminSecret != "" && sessionVariables.Get(XHasuraAdminSecret) != "" {
+		sessionVariables.Set(XHasuraAdminSecret, config.AdminSecret)
+	}
+	if config.Endpoint == "" || config.Endpoint == c.endpoint {
+		return c.Client, c.endpoint, sessionVariables, nil
+	}
+	span.SetAttributes(attribute.String("url", config.Endpoint))
+
+	return c.tenancy.client(config.Endpoint), config.Endpoint, sessionVariables, nil
+}
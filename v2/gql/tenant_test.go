@@ -0,0 +1,109 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestHasuraClient_Tenant(t *testing.T) {
+	var headers []http.Header
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		_, _ = w.Write([]byte(`{"data": {"__typename": "query_root"}}`))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	tenantServer := httptest.NewServer(handler)
+	defer tenantServer.Close()
+
+	client := NewAdminClient(server.URL, "secret",
+		WithTenantResolver(ContextTenantResolver),
+		WithTenantConfigResolver(func(ctx context.Context, tenantID string) (*TenantConfig, error) {
+			switch tenantID {
+			case "dedicated":
+				return &TenantConfig{Endpoint: tenantServer.URL, AdminSecret: "dedicated-secret"}, nil
+			case "unknown":
+				return nil, errors.New("tenant not found")
+			default:
+				return nil, nil
+			}
+		}),
+	)
+	userClient, err := client.AsRole("user", "1")
+	assert.NilError(t, err)
+
+	_, err = userClient.ExecRaw(context.Background(), "query { __typename }", nil)
+	assert.ErrorIs(t, err, ErrTenantRequired)
+	var tenantErr *TenantError
+	assert.Assert(t, errors.As(err, &tenantErr))
+	_, err = client.ExecRaw(context.Background(), "query { __typename }", nil)
+	assert.ErrorIs(t, err, ErrTenantRequired)
+	assert.Equal(t, 0, len(headers))
+
+	_, err = userClient.ExecRaw(NewTenantContext(context.Background(), "shared"), "query { __typename }", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, "shared", headers[0].Get(XHasuraTenantID))
+	assert.Equal(t, "secret", headers[0].Get(XHasuraAdminSecret))
+	assert.Equal(t, "", userClient.sessionVariables.Get(XHasuraTenantID))
+
+	_, err = userClient.ExecRaw(NewTenantContext(context.Background(), "dedicated"), "query { __typename }", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(headers))
+	assert.Equal(t, "dedicated", headers[1].Get(XHasuraTenantID))
+	assert.Equal(t, "dedicated-secret", headers[1].Get(XHasuraAdminSecret))
+
+	_, err = userClient.ExecRaw(NewTenantContext(context.Background(), "unknown"), "query { __typename }", nil)
+	assert.Assert(t, errors.As(err, &tenantErr))
+	assert.Equal(t, "unknown", tenantErr.TenantID)
+
+	crossTenantClient := NewAdminClient(server.URL, "secret", WithTenantResolver(ContextTenantResolver), WithCrossTenant())
+	_, err = crossTenantClient.ExecRaw(context.Background(), "query { __typename }", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, "", headers[2].Get(XHasuraTenantID))
+
+	crossTenantUser, err := crossTenantClient.AsRole("user", "1")
+	assert.NilError(t, err)
+	_, err = crossTenantUser.ExecRaw(context.Background(), "query { __typename }", nil)
+	assert.ErrorIs(t, err, ErrTenantRequired)
+
+	// the per-call role is checked instead of the client role
+	_, err = crossTenantClient.ExecRaw(context.Background(), "query { __typename }", nil, CallHeader(XHasuraRole, "user"))
+	assert.ErrorIs(t, err, ErrTenantRequired)
+	assert.Equal(t, 3, len(headers))
+
+	// the tenant session variable isn't sent unless the session tenant is enabled
+	_, err = crossTenantClient.ExecRaw(context.Background(), "query { __typename }", nil, CallHeader(XHasuraTenantID, "shared"))
+	assert.NilError(t, err)
+	assert.Equal(t, "", headers[3].Get(XHasuraTenantID))
+}
+
+func TestHasuraClient_SessionTenant(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"data": {"__typename": "query_root"}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewAdminClient(server.URL, "secret", WithTenantResolver(ContextTenantResolver)).
+		With(WithRole("user"), WithHeader(XHasuraTenantID, "shared"))
+	assert.NilError(t, err)
+	_, err = client.ExecRaw(context.Background(), "query { __typename }", nil)
+	assert.ErrorIs(t, err, ErrTenantRequired)
+
+	client, err = NewAdminClient(server.URL, "secret", WithTenantResolver(ContextTenantResolver), WithSessionTenant()).
+		With(WithRole("user"), WithHeader(XHasuraTenantID, "shared"))
+	assert.NilError(t, err)
+	_, err = client.ExecRaw(context.Background(), "query { __typename }", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, "shared", header.Get(XHasuraTenantID))
+
+	_, err = client.ExecRaw(NewTenantContext(context.Background(), "other"), "query { __typename }", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, "other", header.Get(XHasuraTenantID))
+}
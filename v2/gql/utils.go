@@ -2,6 +2,8 @@ package gql
 
 import (
 	"context"
+	"slices"
+	"strings"
 
 	"github.com/hasura/go-graphql-client"
 )
@@ -14,10 +16,14 @@ const (
 	XHasuraUserID                    = "x-hasura-user-id"
 	XHasuraAdminSecret               = "x-hasura-admin-secret"
 	XHasuraRole                      = "x-hasura-role"
+	XHasuraAllowedRoles              = "x-hasura-allowed-roles"
+	XHasuraDefaultRole               = "x-hasura-default-role"
 	XRequestId                       = "x-request-id"
 	XHasuraUseBackendOnlyPermissions = "x-hasura-use-backend-only-permissions"
 
 	RoleAdmin string = "admin"
+
+	authorizationHeader = "authorization"
 )
 
 // getHeadersFromContext get request headers from the context
@@ -32,13 +38,14 @@ func getHeadersFromContext(ctx context.Context) map[string]string {
 	return headers
 }
 
+// setHeaders returns a context with the headers merged into a copy of headers in the parent context,
+// so per-request headers never leak into the parent
 func setHeaders(ctx context.Context, hs map[string]string) context.Context {
-	h := ctx.Value(headerKey)
-	var headers map[string]string
-	if h == nil {
-		headers = map[string]string{}
-	} else {
-		headers = h.(map[string]string)
+	headers := map[string]string{}
+	if h, ok := ctx.Value(headerKey).(map[string]string); ok {
+		for k, v := range h {
+			headers[k] = v
+		}
 	}
 	for k, v := range hs {
 		headers[k] = v
@@ -54,3 +61,30 @@ func getOperationNameFromOptions(options []graphql.Option) string {
 	}
 	return ""
 }
+
+const (
+	operationQuery        = "query"
+	operationMutation     = "mutation"
+	operationSubscription = "subscription"
+)
+
+// getOperationType gets the operation type from the raw query string.
+// Documents with any mutation are mutations. The prefix is checked if the document can't be parsed
+func getOperationType(query string) string {
+	if operationTypes, err := parseOperationTypes(query); err == nil {
+		for _, opType := range []string{operationMutation, operationSubscription} {
+			if slices.Contains(operationTypes, opType) {
+				return opType
+			}
+		}
+		return operationQuery
+	}
+
+	query = strings.TrimSpace(query)
+	for _, opType := range []string{operationMutation, operationSubscription} {
+		if strings.HasPrefix(query, opType) {
+			return opType
+		}
+	}
+	return operationQuery
+}
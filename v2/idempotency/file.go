@@ -0,0 +1,226 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore a local IdempotencyStore implementation that persists every record as a JSON file in a directory,
+// so completed results survive process restarts. Processes on the same host may share the directory,
+// records are removed only if they are still the ones that were read. The handler may still run twice
+// if a third process claims the key in the short window while a newer record is put back
+type FileStore struct {
+	dir string
+	mu  sync.RWMutex
+	now func() time.Time
+}
+
+var _ IdempotencyStore = (*FileStore)(nil)
+
+// NewFileStore creates a file store in the directory. The directory is created if it doesn't exist
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create idempotency store directory: %w", err)
+	}
+
+	return &FileStore{
+		dir: dir,
+		now: time.Now,
+	}, nil
+}
+
+// Get returns the record of the key
+func (fs *FileStore) Get(ctx context.Context, key string) (*Record, bool, error) {
+	fs.mu.RLock()
+	record, err := fs.read(key)
+	fs.mu.RUnlock()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	if record.IsExpired(fs.now()) {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		return nil, false, fs.remove(*record)
+	}
+
+	return record, true, nil
+}
+
+// Claim stores the record if the key doesn't exist or is expired.
+// The record file is hard-linked into place, so only one of the processes that share the directory gets the claim
+func (fs *FileStore) Claim(ctx context.Context, record Record) (*Record, bool, error) {
+	bs, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	tmp, err := fs.writeTemp(bs)
+	if err != nil {
+		return nil, false, err
+	}
+	defer os.Remove(tmp)
+
+	path := fs.path(record.Key)
+	for {
+		err := os.Link(tmp, path)
+		if err == nil {
+			return nil, true, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, false, err
+		}
+
+		existing, err := fs.read(record.Key)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if !existing.IsExpired(fs.now()) {
+			return existing, false, nil
+		}
+		if err := fs.remove(*existing); err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+// Set stores the completed record
+func (fs *FileStore) Set(ctx context.Context, record Record) error {
+	bs, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	tmp, err := fs.writeTemp(bs)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, fs.path(record.Key)); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Release removes the pending record of the key if it still holds the token of the claim
+func (fs *FileStore) Release(ctx context.Context, claim Record) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	existing, err := fs.read(claim.Key)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !existing.Pending || existing.Token != claim.Token {
+		return nil
+	}
+	return fs.remove(*existing)
+}
+
+// Delete removes the record of the key
+func (fs *FileStore) Delete(ctx context.Context, key string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	err := os.Remove(fs.path(key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// remove removes the record file only if it still holds the record. Another process may replace the file
+// after the record is read, so the file is renamed away before it is compared and restored if it is newer
+func (fs *FileStore) remove(record Record) error {
+	stale, err := fs.writeTemp(nil)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(stale)
+
+	path := fs.path(record.Key)
+	if err := os.Rename(path, stale); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	current, err := fs.readFile(stale)
+	if err == nil && isSameRecord(*current, record) {
+		return nil
+	}
+	if err := os.Link(stale, path); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// isSameRecord checks if both records are written by the same execution
+func isSameRecord(a Record, b Record) bool {
+	return a.Token == b.Token && a.Pending == b.Pending &&
+		a.CompletedAt.Equal(b.CompletedAt) && a.ExpiresAt.Equal(b.ExpiresAt)
+}
+
+func (fs *FileStore) read(key string) (*Record, error) {
+	return fs.readFile(fs.path(key))
+}
+
+func (fs *FileStore) readFile(path string) (*Record, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var record Record
+	if err := json.Unmarshal(bs, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotency record %s: %w", filepath.Base(path), err)
+	}
+	return &record, nil
+}
+
+// writeTemp writes the data to a temporary file in the store directory and returns the file path
+func (fs *FileStore) writeTemp(data []byte) (string, error) {
+	tmp, err := os.CreateTemp(fs.dir, ".record-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+func (fs *FileStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(fs.dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,269 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hgiasac/hasura-router/go/action"
+	"github.com/hgiasac/hasura-router/go/cron"
+	"github.com/hgiasac/hasura-router/go/event"
+	"github.com/hgiasac/hasura-router/go/types"
+)
+
+const xHasuraUserID = "x-hasura-user-id"
+
+// ErrInProgress is returned when another process that shares the store is running the handler of the key
+var ErrInProgress = errors.New("idempotency key is in progress")
+
+type options struct {
+	ttl       time.Duration
+	claimTTL  time.Duration
+	keyPrefix string
+	headerKey string
+}
+
+var defaultOptions = options{
+	ttl:       24 * time.Hour,
+	claimTTL:  5 * time.Minute,
+	headerKey: IdempotencyKeyHeader,
+}
+
+// Option the optional setting function of the idempotency wrapper
+type Option func(*options)
+
+// WithTTL sets how long completed results are remembered. Zero means forever
+func WithTTL(ttl time.Duration) Option {
+	return func(opts *options) {
+		opts.ttl = ttl
+	}
+}
+
+// WithClaimTTL sets how long the claim of a running execution blocks other processes,
+// so that the key can be retried if the process crashes. Zero means the claim never expires
+func WithClaimTTL(ttl time.Duration) Option {
+	return func(opts *options) {
+		opts.claimTTL = ttl
+	}
+}
+
+// WithKeyPrefix sets the prefix of stored keys, useful when many services share the same store
+func WithKeyPrefix(prefix string) Option {
+	return func(opts *options) {
+		opts.keyPrefix = prefix
+	}
+}
+
+// WithHeaderKey overrides the http header name that carries the idempotency key of action requests
+func WithHeaderKey(name string) Option {
+	return func(opts *options) {
+		opts.headerKey = name
+	}
+}
+
+type inflightCall struct {
+	done   chan struct{}
+	result json.RawMessage
+	err    error
+}
+
+// Idempotency wraps Hasura event, cron and action handlers so that redelivered requests
+// replay the remembered result instead of running the handler again
+type Idempotency struct {
+	store    IdempotencyStore
+	options  options
+	mu       sync.Mutex
+	inflight map[string]*inflightCall
+	now      func() time.Time
+}
+
+// New creates an idempotency wrapper with the store
+func New(store IdempotencyStore, opts ...Option) *Idempotency {
+	options := defaultOptions
+	for _, apply := range opts {
+		apply(&options)
+	}
+
+	return &Idempotency{
+		store:    store,
+		options:  options,
+		inflight: make(map[string]*inflightCall),
+		now:      time.Now,
+	}
+}
+
+// Do runs the function once per key and returns the result encoded as json.RawMessage,
+// both on the first execution and on replays of the remembered result.
+// Concurrent calls with the same key wait for the running one and share its result.
+// The key is claimed in the store before the function runs, so calls of other processes that share the store
+// get ErrInProgress until it completes. Failed executions aren't remembered so the next delivery can retry
+func (idp *Idempotency) Do(ctx context.Context, key string, fn func() (any, error)) (any, error) {
+	key = idp.options.keyPrefix + key
+
+	idp.mu.Lock()
+	if call, ok := idp.inflight[key]; ok {
+		idp.mu.Unlock()
+		select {
+		case <-call.done:
+			if call.err != nil {
+				return nil, call.err
+			}
+			return call.result, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &inflightCall{done: make(chan struct{})}
+	idp.inflight[key] = call
+	idp.mu.Unlock()
+
+	defer func() {
+		// waiting calls fail if the function panics, the panic is propagated to the caller
+		r := recover()
+		if r != nil {
+			call.result, call.err = nil, fmt.Errorf("idempotency handler panicked: %v", r)
+		}
+		idp.mu.Lock()
+		delete(idp.inflight, key)
+		idp.mu.Unlock()
+		close(call.done)
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	call.result, call.err = idp.do(ctx, key, fn)
+	if call.err != nil {
+		return nil, call.err
+	}
+	return call.result, nil
+}
+
+func (idp *Idempotency) do(ctx context.Context, key string, fn func() (any, error)) (json.RawMessage, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate idempotency claim token: %w", err)
+	}
+	now := idp.now()
+	claim := Record{
+		Key:     key,
+		Pending: true,
+		Token:   token,
+	}
+	if idp.options.claimTTL > 0 {
+		claim.ExpiresAt = now.Add(idp.options.claimTTL)
+	}
+	record, ok, err := idp.store.Claim(ctx, claim)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if !ok {
+		if record.Pending {
+			return nil, ErrInProgress
+		}
+		return record.Result, nil
+	}
+
+	completed := false
+	defer func() {
+		// release the claim so that the next delivery can retry
+		if !completed {
+			_ = idp.store.Release(context.WithoutCancel(ctx), claim)
+		}
+	}()
+
+	result, err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode idempotency record: %w", err)
+	}
+
+	now = idp.now()
+	record = &Record{
+		Key:         key,
+		Result:      bs,
+		Token:       token,
+		CompletedAt: now,
+	}
+	if idp.options.ttl > 0 {
+		record.ExpiresAt = now.Add(idp.options.ttl)
+	}
+	if err := idp.store.Set(ctx, *record); err != nil {
+		return nil, fmt.Errorf("failed to store idempotency record: %w", err)
+	}
+	completed = true
+
+	return bs, nil
+}
+
+// WrapEventHandler wraps the event trigger handler, keyed by the trigger name and event id
+func (idp *Idempotency) WrapEventHandler(handler event.Handler) event.Handler {
+	return func(ctx *event.Context, payload event.EventTriggerPayload) (interface{}, error) {
+		key := fmt.Sprintf("event:%s:%s", payload.Trigger.Name, payload.ID)
+		return idp.Do(contextOrBackground(ctx.Context), key, func() (any, error) {
+			return handler(ctx, payload)
+		})
+	}
+}
+
+// WrapCronHandler wraps the scheduled event handler, keyed by the event id
+func (idp *Idempotency) WrapCronHandler(handler cron.Handler) cron.Handler {
+	return func(ctx *cron.Context, payload cron.EventPayload) (interface{}, error) {
+		key := fmt.Sprintf("cron:%s:%s", payload.Name, payload.ID)
+		return idp.Do(contextOrBackground(ctx.Context), key, func() (any, error) {
+			return handler(ctx, payload)
+		})
+	}
+}
+
+// WrapAction wraps the action handler, keyed by the Idempotency-Key header and scoped to the role and user id
+// of the session, so users can't replay results of each other. Requests without the header are executed as usual
+func (idp *Idempotency) WrapAction(handler action.Action) action.Action {
+	return func(ctx *action.Context, rawBody []byte) (interface{}, error) {
+		idempotencyKey := ctx.Headers.Get(idp.options.headerKey)
+		if idempotencyKey == "" {
+			return handler(ctx, rawBody)
+		}
+
+		var body struct {
+			Action struct {
+				Name string `json:"name"`
+			} `json:"action"`
+			SessionVariables map[string]string `json:"session_variables"`
+		}
+		_ = json.Unmarshal(rawBody, &body)
+		sessionVariables := types.NewSessionVariables(body.SessionVariables)
+
+		// role and user id are quoted so values with the separator can't collide
+		key := fmt.Sprintf("action:%s:%q:%q:%s", body.Action.Name,
+			sessionVariables.GetRole(), sessionVariables.Get(xHasuraUserID), idempotencyKey)
+		return idp.Do(contextOrBackground(ctx.Context), key, func() (any, error) {
+			return handler(ctx, rawBody)
+		})
+	}
+}
+
+func newToken() (string, error) {
+	bs := make([]byte, 16)
+	if _, err := rand.Read(bs); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bs), nil
+}
+
+// hasura router handlers may receive a context without the embedded parent context
+func contextOrBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
@@ -0,0 +1,291 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hgiasac/hasura-router/go/action"
+	"github.com/hgiasac/hasura-router/go/event"
+	"gotest.tools/v3/assert"
+)
+
+func TestIdempotency_EventHandler(t *testing.T) {
+	for name, store := range map[string]func(t *testing.T) IdempotencyStore{
+		"memory": func(t *testing.T) IdempotencyStore {
+			return NewMemoryStore(10)
+		},
+		"file": func(t *testing.T) IdempotencyStore {
+			fs, err := NewFileStore(t.TempDir())
+			assert.NilError(t, err)
+			return fs
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var count int32
+			handler := New(store(t)).WrapEventHandler(func(ctx *event.Context, payload event.EventTriggerPayload) (interface{}, error) {
+				atomic.AddInt32(&count, 1)
+				return map[string]string{"id": payload.ID}, nil
+			})
+
+			payload := event.EventTriggerPayload{ID: "1", Trigger: event.TriggerInfo{Name: "foo"}}
+			result, err := handler(&event.Context{}, payload)
+			assert.NilError(t, err)
+			assert.Equal(t, `{"id":"1"}`, string(result.(json.RawMessage)))
+
+			replay, err := handler(&event.Context{}, payload)
+			assert.NilError(t, err)
+			assert.Equal(t, `{"id":"1"}`, string(replay.(json.RawMessage)))
+			assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+			payload.ID = "2"
+			_, err = handler(&event.Context{}, payload)
+			assert.NilError(t, err)
+			assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+		})
+	}
+}
+
+func TestIdempotency_ConcurrentDuplicates(t *testing.T) {
+	var count int32
+	release := make(chan struct{})
+	idp := New(NewMemoryStore(0))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := idp.Do(context.Background(), "key", func() (any, error) {
+				atomic.AddInt32(&count, 1)
+				<-release
+				return true, nil
+			})
+			assert.Check(t, err)
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+}
+
+func TestIdempotency_FailureIsNotRemembered(t *testing.T) {
+	var count int32
+	idp := New(NewMemoryStore(0))
+	fn := func() (any, error) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			return nil, errors.New("failure")
+		}
+		return "ok", nil
+	}
+
+	_, err := idp.Do(context.Background(), "key", fn)
+	assert.ErrorContains(t, err, "failure")
+
+	result, err := idp.Do(context.Background(), "key", fn)
+	assert.NilError(t, err)
+	assert.Equal(t, `"ok"`, string(result.(json.RawMessage)))
+}
+
+func TestIdempotency_Panic(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	idp := New(NewMemoryStore(0))
+
+	waiterErr := make(chan error, 1)
+	go func() {
+		<-started
+		_, err := idp.Do(context.Background(), "key", func() (any, error) {
+			return "unreachable", nil
+		})
+		waiterErr <- err
+	}()
+
+	func() {
+		defer func() {
+			assert.Equal(t, "boom", recover())
+		}()
+		_, _ = idp.Do(context.Background(), "key", func() (any, error) {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			panic("boom")
+		})
+	}()
+	<-release
+	assert.ErrorContains(t, <-waiterErr, "idempotency handler panicked: boom")
+
+	// the claim is released so the next delivery can retry
+	result, err := idp.Do(context.Background(), "key", func() (any, error) {
+		return "ok", nil
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, `"ok"`, string(result.(json.RawMessage)))
+}
+
+func TestIdempotency_SharedStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	assert.NilError(t, err)
+	first := New(store)
+	second := New(store)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := first.Do(context.Background(), "key", func() (any, error) {
+			close(started)
+			<-release
+			return "first", nil
+		})
+		done <- err
+	}()
+
+	<-started
+	_, err = second.Do(context.Background(), "key", func() (any, error) {
+		return "second", nil
+	})
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	close(release)
+	assert.NilError(t, <-done)
+	result, err := second.Do(context.Background(), "key", func() (any, error) {
+		return "second", nil
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, `"first"`, string(result.(json.RawMessage)))
+}
+
+func TestIdempotency_Action(t *testing.T) {
+	var count int32
+	handler := New(NewMemoryStore(0)).WrapAction(func(ctx *action.Context, rawBody []byte) (interface{}, error) {
+		atomic.AddInt32(&count, 1)
+		return "ok", nil
+	})
+
+	body := []byte(`{"action":{"name":"foo"}}`)
+	headers := http.Header{}
+	for i := 0; i < 2; i++ {
+		_, err := handler(&action.Context{Headers: headers}, body)
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+
+	headers.Set(IdempotencyKeyHeader, "abc")
+	for i := 0; i < 2; i++ {
+		_, err := handler(&action.Context{Headers: headers}, body)
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+
+	// the key is scoped to the role and user id of the session
+	userBody := []byte(`{"action":{"name":"foo"},"session_variables":{"X-Hasura-Role":"user","x-hasura-user-id":"1"}}`)
+	for i := 0; i < 2; i++ {
+		_, err := handler(&action.Context{Headers: headers}, userBody)
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, int32(4), atomic.LoadInt32(&count))
+
+	for _, otherBody := range []string{
+		`{"action":{"name":"foo"},"session_variables":{"x-hasura-role":"user","x-hasura-user-id":"2"}}`,
+		`{"action":{"name":"foo"},"session_variables":{"x-hasura-role":"editor","x-hasura-user-id":"1"}}`,
+	} {
+		_, err := handler(&action.Context{Headers: headers}, []byte(otherBody))
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, int32(6), atomic.LoadInt32(&count))
+}
+
+func TestMemoryStore_Eviction(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(2)
+	for _, key := range []string{"a", "b", "c"} {
+		assert.NilError(t, store.Set(ctx, Record{Key: key}))
+	}
+	assert.Equal(t, 2, store.Len())
+
+	_, ok, err := store.Get(ctx, "a")
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+
+	assert.NilError(t, store.Set(ctx, Record{Key: "d", ExpiresAt: time.Now().Add(-time.Second)}))
+	_, ok, err = store.Get(ctx, "d")
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+}
+
+func TestStore_Claim(t *testing.T) {
+	ctx := context.Background()
+	fileStore, err := NewFileStore(t.TempDir())
+	assert.NilError(t, err)
+	for name, store := range map[string]IdempotencyStore{
+		"memory": NewMemoryStore(10),
+		"file":   fileStore,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, ok, err := store.Claim(ctx, Record{Key: "a", Pending: true})
+			assert.NilError(t, err)
+			assert.Assert(t, ok)
+
+			existing, ok, err := store.Claim(ctx, Record{Key: "a", Pending: true})
+			assert.NilError(t, err)
+			assert.Assert(t, !ok)
+			assert.Assert(t, existing.Pending)
+
+			// expired claims can be taken over
+			assert.NilError(t, store.Set(ctx, Record{Key: "b", Pending: true, Token: "old", ExpiresAt: time.Now().Add(-time.Second)}))
+			_, ok, err = store.Claim(ctx, Record{Key: "b", Pending: true, Token: "new"})
+			assert.NilError(t, err)
+			assert.Assert(t, ok)
+
+			// the previous owner can't release the claim that was taken over
+			assert.NilError(t, store.Release(ctx, Record{Key: "b", Pending: true, Token: "old"}))
+			existing, ok, err = store.Get(ctx, "b")
+			assert.NilError(t, err)
+			assert.Assert(t, ok)
+			assert.Equal(t, "new", existing.Token)
+
+			assert.NilError(t, store.Release(ctx, Record{Key: "b", Pending: true, Token: "new"}))
+			_, ok, err = store.Get(ctx, "b")
+			assert.NilError(t, err)
+			assert.Assert(t, !ok)
+		})
+	}
+}
+
+func TestFileStore_Takeover(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	// stores with separate locks act as processes that share the directory
+	first, err := NewFileStore(dir)
+	assert.NilError(t, err)
+	second, err := NewFileStore(dir)
+	assert.NilError(t, err)
+
+	expired := Record{Key: "a", Pending: true, Token: "expired", ExpiresAt: time.Now().Add(-time.Second)}
+	assert.NilError(t, first.Set(ctx, expired))
+
+	// both processes read the expired claim, the second one takes it over first
+	_, ok, err := second.Claim(ctx, Record{Key: "a", Pending: true, Token: "second"})
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+
+	// the slower process keeps the newer claim
+	assert.NilError(t, first.remove(expired))
+	existing, ok, err := first.Claim(ctx, Record{Key: "a", Pending: true, Token: "first"})
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+	assert.Equal(t, "second", existing.Token)
+
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(entries))
+}
@@ -0,0 +1,126 @@
+package idempotency
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore an in-memory IdempotencyStore implementation that evicts the least recently used records
+type MemoryStore struct {
+	capacity int
+	mu       sync.Mutex
+	items    map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+var _ IdempotencyStore = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an in-memory LRU store with the maximum number of records.
+// The store is unbounded if the capacity is zero or negative
+func NewMemoryStore(capacity int) *MemoryStore {
+	return &MemoryStore{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get returns the record of the key
+func (ms *MemoryStore) Get(ctx context.Context, key string) (*Record, bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	elem, ok := ms.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	record := elem.Value.(Record)
+	if record.IsExpired(ms.now()) {
+		ms.removeElement(elem)
+		return nil, false, nil
+	}
+	ms.order.MoveToFront(elem)
+
+	return &record, true, nil
+}
+
+// Claim stores the record if the key doesn't exist or is expired
+func (ms *MemoryStore) Claim(ctx context.Context, record Record) (*Record, bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if elem, ok := ms.items[record.Key]; ok {
+		existing := elem.Value.(Record)
+		if !existing.IsExpired(ms.now()) {
+			return &existing, false, nil
+		}
+		ms.removeElement(elem)
+	}
+	ms.set(record)
+
+	return nil, true, nil
+}
+
+// Set stores the completed record
+func (ms *MemoryStore) Set(ctx context.Context, record Record) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.set(record)
+
+	return nil
+}
+
+func (ms *MemoryStore) set(record Record) {
+	if elem, ok := ms.items[record.Key]; ok {
+		elem.Value = record
+		ms.order.MoveToFront(elem)
+		return
+	}
+
+	ms.items[record.Key] = ms.order.PushFront(record)
+	for ms.capacity > 0 && ms.order.Len() > ms.capacity {
+		ms.removeElement(ms.order.Back())
+	}
+}
+
+// Release removes the pending record of the key if it still holds the token of the claim
+func (ms *MemoryStore) Release(ctx context.Context, claim Record) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if elem, ok := ms.items[claim.Key]; ok {
+		existing := elem.Value.(Record)
+		if existing.Pending && existing.Token == claim.Token {
+			ms.removeElement(elem)
+		}
+	}
+	return nil
+}
+
+// Delete removes the record of the key
+func (ms *MemoryStore) Delete(ctx context.Context, key string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if elem, ok := ms.items[key]; ok {
+		ms.removeElement(elem)
+	}
+	return nil
+}
+
+// Len returns the number of stored records
+func (ms *MemoryStore) Len() int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.order.Len()
+}
+
+func (ms *MemoryStore) removeElement(elem *list.Element) {
+	ms.order.Remove(elem)
+	delete(ms.items, elem.Value.(Record).Key)
+}
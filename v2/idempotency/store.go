@@ -0,0 +1,43 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// IdempotencyKeyHeader the http header that carries the idempotency key of an action request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Record represents the remembered result of a completed handler execution,
+// or the claim of a running execution if Pending is true
+type Record struct {
+	Key     string          `json:"key"`
+	Result  json.RawMessage `json:"result"`
+	Pending bool            `json:"pending,omitempty"`
+	// Token identifies the execution that claimed the key
+	Token       string    `json:"token,omitempty"`
+	CompletedAt time.Time `json:"completed_at"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty"`
+}
+
+// IsExpired checks if the record is expired at the input time
+func (r Record) IsExpired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// IdempotencyStore abstracts the storage of completed handler results
+type IdempotencyStore interface {
+	// Get returns the record of the key. The boolean result is false if the key doesn't exist or is expired
+	Get(ctx context.Context, key string) (*Record, bool, error)
+	// Claim stores the record only if the key doesn't exist or is expired, so that one execution runs the handler
+	// across processes that share the store. If the key exists, the existing record is returned with false
+	Claim(ctx context.Context, record Record) (*Record, bool, error)
+	// Set stores the completed record
+	Set(ctx context.Context, record Record) error
+	// Release removes the pending record of the key only if it still holds the token of the claim,
+	// so a claim that another execution took over after it expired is kept
+	Release(ctx context.Context, claim Record) error
+	// Delete removes the record of the key
+	Delete(ctx context.Context, key string) error
+}
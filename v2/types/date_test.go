@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
 )
@@ -56,3 +57,37 @@ func TestDate(t *testing.T) {
 		assert.Assert(t, dateObject.Date == nil)
 	})
 }
+
+func TestTimestamp(t *testing.T) {
+	for _, ip := range []struct {
+		Input   string
+		IsError bool
+		Output  time.Time
+	}{
+		{`"2023-05-31T10:48:43.853829"`, false, time.Date(2023, 5, 31, 10, 48, 43, 853829000, time.UTC)},
+		{`"2023-05-31 10:48:43"`, false, time.Date(2023, 5, 31, 10, 48, 43, 0, time.UTC)},
+		{`"2023-05-31T10:48:43.853829+07:00"`, false, time.Date(2023, 5, 31, 3, 48, 43, 853829000, time.UTC)},
+		{`"2023-05-31"`, true, time.Time{}},
+	} {
+		t.Run(ip.Input, func(t *testing.T) {
+			var result Timestamp
+			err := json.Unmarshal([]byte(ip.Input), &result)
+			if ip.IsError {
+				assert.ErrorContains(t, err, "invalid timestamp")
+			} else {
+				assert.NilError(t, err)
+				assert.Assert(t, ip.Output.Equal(result.Time), result.Time)
+			}
+		})
+	}
+
+	var object struct {
+		CreatedAt *Timestamp `json:"created_at"`
+	}
+	assert.NilError(t, json.Unmarshal([]byte(`{"created_at": null}`), &object))
+	assert.Assert(t, object.CreatedAt == nil)
+
+	bs, err := json.Marshal(Timestamp{Time: time.Date(2023, 5, 31, 10, 48, 43, 0, time.UTC)})
+	assert.NilError(t, err)
+	assert.Equal(t, `"2023-05-31T10:48:43Z"`, string(bs))
+}
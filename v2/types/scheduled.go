@@ -0,0 +1,60 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hgiasac/hasura-router/go/cron"
+)
+
+// ScheduledEventPayload represents the webhook payload of Hasura one-off scheduled events
+type ScheduledEventPayload[T any] struct {
+	ID            string    `json:"id"`
+	Comment       string    `json:"comment"`
+	ScheduledTime time.Time `json:"scheduled_time"`
+	Payload       T         `json:"payload"`
+}
+
+// CronPayload represents the webhook payload of Hasura cron triggers
+type CronPayload[T any] struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Comment       string    `json:"comment"`
+	ScheduledTime time.Time `json:"scheduled_time"`
+	Payload       T         `json:"payload"`
+}
+
+// DecodeScheduledEventPayload decodes the one-off scheduled event webhook body with the typed payload
+func DecodeScheduledEventPayload[T any](body []byte) (*ScheduledEventPayload[T], error) {
+	var result ScheduledEventPayload[T]
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, ErrDecodeJSON(err, nil)
+	}
+	return &result, nil
+}
+
+// DecodeCronPayload decodes the cron trigger webhook body with the typed payload, including the comment
+func DecodeCronPayload[T any](body []byte) (*CronPayload[T], error) {
+	var result CronPayload[T]
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, ErrDecodeJSON(err, nil)
+	}
+	return &result, nil
+}
+
+// NewCronPayload converts the hasura router cron payload to the typed payload.
+// The router payload doesn't carry the comment of the cron trigger, use DecodeCronPayload with the webhook body to get it
+func NewCronPayload[T any](payload cron.EventPayload) (*CronPayload[T], error) {
+	result := CronPayload[T]{
+		ID:            payload.ID,
+		Name:          payload.Name,
+		ScheduledTime: payload.ScheduledTime,
+	}
+	if len(payload.Payload) > 0 && string(payload.Payload) != "null" {
+		if err := json.Unmarshal(payload.Payload, &result.Payload); err != nil {
+			return nil, ErrDecodeJSON(err, nil)
+		}
+	}
+
+	return &result, nil
+}
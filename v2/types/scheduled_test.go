@@ -0,0 +1,67 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hgiasac/hasura-router/go/cron"
+	"gotest.tools/v3/assert"
+)
+
+type reminderPayload struct {
+	Email string `json:"email"`
+	Due   Date   `json:"due"`
+}
+
+func TestScheduledEventPayload(t *testing.T) {
+	result, err := DecodeScheduledEventPayload[reminderPayload]([]byte(`{
+		"id": "1",
+		"comment": "reminder",
+		"scheduled_time": "2024-05-01T10:00:00Z",
+		"payload": { "email": "foo@example.com", "due": "2024-05-02" }
+	}`))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ScheduledEventPayload[reminderPayload]{
+		ID:            "1",
+		Comment:       "reminder",
+		ScheduledTime: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Payload: reminderPayload{
+			Email: "foo@example.com",
+			Due:   Date{2024, 5, 2},
+		},
+	}, *result)
+
+	_, err = DecodeScheduledEventPayload[reminderPayload]([]byte(`{"payload": {"due": "2024-02-30"}}`))
+	assert.ErrorContains(t, err, "invalid date `2024-02-30`")
+}
+
+func TestCronPayload(t *testing.T) {
+	result, err := NewCronPayload[reminderPayload](cron.EventPayload{
+		ID:      "1",
+		Name:    "daily",
+		Payload: json.RawMessage(`{"email": "foo@example.com", "due": "2024-05-02"}`),
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, "daily", result.Name)
+	assert.Equal(t, Date{2024, 5, 2}, result.Payload.Due)
+
+	result, err = DecodeCronPayload[reminderPayload]([]byte(`{
+		"id": "1",
+		"name": "daily",
+		"comment": "daily reminder",
+		"scheduled_time": "2024-05-01T10:00:00Z",
+		"payload": { "email": "foo@example.com", "due": "2024-05-02" }
+	}`))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, CronPayload[reminderPayload]{
+		ID:            "1",
+		Name:          "daily",
+		Comment:       "daily reminder",
+		ScheduledTime: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Payload: reminderPayload{
+			Email: "foo@example.com",
+			Due:   Date{2024, 5, 2},
+		},
+	}, *result)
+}
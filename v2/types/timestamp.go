@@ -0,0 +1,53 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timestampLayouts the layouts of Postgres timestamp values without time zone
+var timestampLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// Timestamp represents a time object which is compatible with both Postgres timestamp and timestamptz types.
+// Values without time zone are treated as UTC
+type Timestamp struct {
+	time.Time
+}
+
+// UnmarshalJSON implements the json Unmarshaler interface
+func (ts *Timestamp) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	result, err := ParseTimestamp(strings.Trim(s, "\""))
+	if err != nil {
+		return err
+	}
+	*ts = *result
+	return nil
+}
+
+// MarshalJSON implements the json Marshaler interface
+func (ts Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ts.Format(time.RFC3339Nano))
+}
+
+// ParseTimestamp parses timestamp from string with or without time zone
+func ParseTimestamp(input string) (*Timestamp, error) {
+	if t, err := time.Parse(time.RFC3339Nano, input); err == nil {
+		return &Timestamp{Time: t}, nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, input, time.UTC); err == nil {
+			return &Timestamp{Time: t}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid timestamp `%s`", input)
+}